@@ -0,0 +1,483 @@
+package asche
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define ASCHE_SCOPES 5
+
+// asche_allocation_callbacks mirrors VkAllocationCallbacks.
+typedef struct {
+	void *pUserData;
+	void *(*pfnAllocation)(void *, size_t, size_t, int);
+	void *(*pfnReallocation)(void *, void *, size_t, size_t, int);
+	void (*pfnFree)(void *, void *);
+	void (*pfnInternalAllocation)(void *, size_t, int, int);
+	void (*pfnInternalFree)(void *, size_t, int, int);
+} asche_allocation_callbacks;
+
+typedef struct {
+	int64_t bytes[ASCHE_SCOPES];
+	int64_t count[ASCHE_SCOPES];
+	int64_t internal_bytes;
+	int64_t internal_count;
+} asche_allocation_stats;
+
+// asche_header precedes every allocation, keeping what is needed to free and reallocate it.
+typedef struct {
+	void *base;
+	size_t size;
+	int scope;
+} asche_header;
+
+// the returned memory is aligned to at least a pointer, so is the header right before it
+_Static_assert(sizeof(asche_header) % sizeof(void *) == 0, "asche_header breaks the alignment");
+
+static void asche_count(asche_allocation_stats *stats, int scope, int64_t bytes, int64_t count) {
+	if (scope < 0 || scope >= ASCHE_SCOPES) {
+		scope = 0;
+	}
+	__atomic_add_fetch(&stats->bytes[scope], bytes, __ATOMIC_RELAXED);
+	__atomic_add_fetch(&stats->count[scope], count, __ATOMIC_RELAXED);
+}
+
+static void *asche_allocation(void *user, size_t size, size_t alignment, int scope) {
+	if (alignment < sizeof(void *)) {
+		alignment = sizeof(void *);
+	}
+	void *base = malloc(size + sizeof(asche_header) + alignment);
+	if (base == NULL) {
+		return NULL;
+	}
+	// alignment is a power of two, rounding up base + sizeof(asche_header) leaves room for the header
+	// after base and adds less than alignment bytes, so the memory ends within the block
+	uintptr_t mem = ((uintptr_t)base + sizeof(asche_header) + alignment - 1) & ~(uintptr_t)(alignment - 1);
+	asche_header *h = (asche_header *)mem - 1;
+	h->base = base;
+	h->size = size;
+	h->scope = scope;
+	asche_count(user, scope, size, 1);
+	return (void *)mem;
+}
+
+static void asche_free(void *user, void *mem) {
+	if (mem == NULL) {
+		return;
+	}
+	asche_header *h = (asche_header *)mem - 1;
+	asche_count(user, h->scope, -(int64_t)h->size, -1);
+	free(h->base);
+}
+
+static void *asche_reallocation(void *user, void *orig, size_t size, size_t alignment, int scope) {
+	if (orig == NULL) {
+		return asche_allocation(user, size, alignment, scope);
+	}
+	if (size == 0) {
+		asche_free(user, orig);
+		return NULL;
+	}
+	void *mem = asche_allocation(user, size, alignment, scope);
+	if (mem == NULL) {
+		return NULL;
+	}
+	asche_header *h = (asche_header *)orig - 1;
+	memcpy(mem, orig, h->size < size ? h->size : size);
+	asche_free(user, orig);
+	return mem;
+}
+
+static void asche_internal_allocation(void *user, size_t size, int type, int scope) {
+	asche_allocation_stats *stats = user;
+	__atomic_add_fetch(&stats->internal_bytes, (int64_t)size, __ATOMIC_RELAXED);
+	__atomic_add_fetch(&stats->internal_count, 1, __ATOMIC_RELAXED);
+}
+
+static void asche_internal_free(void *user, size_t size, int type, int scope) {
+	asche_allocation_stats *stats = user;
+	__atomic_add_fetch(&stats->internal_bytes, -(int64_t)size, __ATOMIC_RELAXED);
+	__atomic_add_fetch(&stats->internal_count, -1, __ATOMIC_RELAXED);
+}
+
+// asche_new_callbacks returns NULL if out of memory.
+static asche_allocation_callbacks *asche_new_callbacks(void) {
+	asche_allocation_callbacks *cb = calloc(1, sizeof(asche_allocation_callbacks));
+	if (cb == NULL) {
+		return NULL;
+	}
+	cb->pUserData = calloc(1, sizeof(asche_allocation_stats));
+	if (cb->pUserData == NULL) {
+		free(cb);
+		return NULL;
+	}
+	cb->pfnAllocation = asche_allocation;
+	cb->pfnReallocation = asche_reallocation;
+	cb->pfnFree = asche_free;
+	cb->pfnInternalAllocation = asche_internal_allocation;
+	cb->pfnInternalFree = asche_internal_free;
+	return cb;
+}
+
+static void asche_free_callbacks(asche_allocation_callbacks *cb) {
+	free(cb->pUserData);
+	free(cb);
+}
+
+static void asche_load_stats(asche_allocation_callbacks *cb, asche_allocation_stats *out) {
+	asche_allocation_stats *stats = cb->pUserData;
+	for (int i = 0; i < ASCHE_SCOPES; i++) {
+		out->bytes[i] = __atomic_load_n(&stats->bytes[i], __ATOMIC_RELAXED);
+		out->count[i] = __atomic_load_n(&stats->count[i], __ATOMIC_RELAXED);
+	}
+	out->internal_bytes = __atomic_load_n(&stats->internal_bytes, __ATOMIC_RELAXED);
+	out->internal_count = __atomic_load_n(&stats->internal_count, __ATOMIC_RELAXED);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"sync"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// HostMemory is the host memory allocated by the driver and not freed yet.
+type HostMemory struct {
+	Bytes int64
+	Count int64
+}
+
+// HostAllocations is the host memory held by the driver for a subsystem.
+type HostAllocations struct {
+	// Scopes is the memory allocated through the callbacks, indexed by vk.SystemAllocationScope.
+	Scopes [vk.SystemAllocationScopeInstance + 1]HostMemory
+	// Internal is the memory the driver allocated on its own and reported, e.g. executable memory.
+	Internal HostMemory
+}
+
+// Total gets the memory of all the scopes, including the internal allocations.
+func (a *HostAllocations) Total() HostMemory {
+	total := a.Internal
+	for _, m := range a.Scopes {
+		total.Bytes += m.Bytes
+		total.Count += m.Count
+	}
+	return total
+}
+
+// AllocationStats is the host memory held by the driver per subsystem, see ApplicationAllocationStats.
+type AllocationStats struct {
+	// Instance is the memory of the instance and the debug callback.
+	Instance HostAllocations
+	// Device is the memory of the logical device.
+	Device HostAllocations
+	// Objects is the memory of the objects created on the device, e.g. buffers, shader modules or the swapchain.
+	Objects HostAllocations
+}
+
+// hostAllocator is a driver that passes allocation callbacks counting the host memory per subsystem
+// to the calls made with a nil allocator, objects are destroyed with the callbacks they were created with.
+type hostAllocator struct {
+	driver.Driver
+
+	mu       sync.Mutex
+	instance *C.asche_allocation_callbacks
+	device   *C.asche_allocation_callbacks
+	objects  *C.asche_allocation_callbacks
+	created  map[leakKey]*vk.AllocationCallbacks
+	released bool
+}
+
+// newHostAllocator counts the host memory allocated by the driver for the calls made through it.
+func newHostAllocator(d driver.Driver) (*hostAllocator, error) {
+	a := &hostAllocator{
+		Driver:   d,
+		instance: C.asche_new_callbacks(),
+		device:   C.asche_new_callbacks(),
+		objects:  C.asche_new_callbacks(),
+		created:  make(map[leakKey]*vk.AllocationCallbacks),
+	}
+	if a.instance == nil || a.device == nil || a.objects == nil {
+		a.freeCallbacks()
+		return nil, errors.New("vulkan error: out of host memory for the allocation callbacks")
+	}
+	return a, nil
+}
+
+// release stops the stats, the callbacks are freed once the objects created with them are destroyed.
+func (a *hostAllocator) release() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.released = true
+	if len(a.created) == 0 {
+		a.freeCallbacks()
+	}
+}
+
+// freeCallbacks must be called with mu held, or before the allocator is shared.
+func (a *hostAllocator) freeCallbacks() {
+	for _, cb := range []*C.asche_allocation_callbacks{a.instance, a.device, a.objects} {
+		if cb != nil {
+			C.asche_free_callbacks(cb)
+		}
+	}
+	a.instance, a.device, a.objects = nil, nil, nil
+}
+
+func callbacks(cb *C.asche_allocation_callbacks) *vk.AllocationCallbacks {
+	return (*vk.AllocationCallbacks)(unsafe.Pointer(cb))
+}
+
+func loadAllocations(cb *C.asche_allocation_callbacks) HostAllocations {
+	var stats C.asche_allocation_stats
+	C.asche_load_stats(cb, &stats)
+	var a HostAllocations
+	for i := range a.Scopes {
+		a.Scopes[i] = HostMemory{
+			Bytes: int64(stats.bytes[i]),
+			Count: int64(stats.count[i]),
+		}
+	}
+	a.Internal = HostMemory{
+		Bytes: int64(stats.internal_bytes),
+		Count: int64(stats.internal_count),
+	}
+	return a
+}
+
+// stats gets the current statistics, it's nil once released.
+func (a *hostAllocator) stats() *AllocationStats {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.released {
+		return nil
+	}
+	return &AllocationStats{
+		Instance: loadAllocations(a.instance),
+		Device:   loadAllocations(a.device),
+		Objects:  loadAllocations(a.objects),
+	}
+}
+
+// create gets the callbacks of the subsystem and remembers them for the object once created.
+func (a *hostAllocator) create(cb *C.asche_allocation_callbacks) (pAllocator *vk.AllocationCallbacks,
+	done func(ret vk.Result, object interface{})) {
+
+	pAllocator = callbacks(cb)
+	return pAllocator, func(ret vk.Result, object interface{}) {
+		key, ok := handleKey(object)
+		if ret != vk.Success || !ok {
+			return
+		}
+		a.mu.Lock()
+		a.created[key] = pAllocator
+		a.mu.Unlock()
+	}
+}
+
+// destroy gets the callbacks the object was created with, it's nil for objects created elsewhere.
+// The returned func forgets the object once destroyed, the callbacks of a released allocator
+// are freed along with its last object.
+func (a *hostAllocator) destroy(object interface{}) (pAllocator *vk.AllocationCallbacks, done func()) {
+	key, ok := handleKey(object)
+	if !ok {
+		return nil, func() {}
+	}
+	a.mu.Lock()
+	pAllocator = a.created[key]
+	a.mu.Unlock()
+	return pAllocator, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		delete(a.created, key)
+		if a.released && len(a.created) == 0 {
+			a.freeCallbacks()
+		}
+	}
+}
+
+func (a *hostAllocator) AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo, pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.AllocateMemory(device, pAllocateInfo, pAllocator, pMemory)
+	done(ret, *pMemory)
+	return ret
+}
+
+func (a *hostAllocator) CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo, pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateBuffer(device, pCreateInfo, pAllocator, pBuffer)
+	done(ret, *pBuffer)
+	return ret
+}
+
+func (a *hostAllocator) CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo, pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateCommandPool(device, pCreateInfo, pAllocator, pCommandPool)
+	done(ret, *pCommandPool)
+	return ret
+}
+
+func (a *hostAllocator) CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo, pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result {
+	pAllocator, done := a.create(a.instance)
+	ret := a.Driver.CreateDebugReportCallback(instance, pCreateInfo, pAllocator, pCallback)
+	done(ret, *pCallback)
+	return ret
+}
+
+func (a *hostAllocator) CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo, pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result {
+	pAllocator, done := a.create(a.device)
+	ret := a.Driver.CreateDevice(physicalDevice, pCreateInfo, pAllocator, pDevice)
+	done(ret, *pDevice)
+	return ret
+}
+
+func (a *hostAllocator) CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo, pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateFence(device, pCreateInfo, pAllocator, pFence)
+	done(ret, *pFence)
+	return ret
+}
+
+func (a *hostAllocator) CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo, pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateFramebuffer(device, pCreateInfo, pAllocator, pFramebuffer)
+	done(ret, *pFramebuffer)
+	return ret
+}
+
+func (a *hostAllocator) CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo, pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateImage(device, pCreateInfo, pAllocator, pImage)
+	done(ret, *pImage)
+	return ret
+}
+
+func (a *hostAllocator) CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo, pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateImageView(device, pCreateInfo, pAllocator, pView)
+	done(ret, *pView)
+	return ret
+}
+
+func (a *hostAllocator) CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks, pInstance *vk.Instance) vk.Result {
+	pAllocator, done := a.create(a.instance)
+	ret := a.Driver.CreateInstance(pCreateInfo, pAllocator, pInstance)
+	done(ret, *pInstance)
+	return ret
+}
+
+func (a *hostAllocator) CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo, pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateRenderPass(device, pCreateInfo, pAllocator, pRenderPass)
+	done(ret, *pRenderPass)
+	return ret
+}
+
+func (a *hostAllocator) CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo, pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateSemaphore(device, pCreateInfo, pAllocator, pSemaphore)
+	done(ret, *pSemaphore)
+	return ret
+}
+
+func (a *hostAllocator) CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo, pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateShaderModule(device, pCreateInfo, pAllocator, pShaderModule)
+	done(ret, *pShaderModule)
+	return ret
+}
+
+func (a *hostAllocator) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo, pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+	pAllocator, done := a.create(a.objects)
+	ret := a.Driver.CreateSwapchain(device, pCreateInfo, pAllocator, pSwapchain)
+	done(ret, *pSwapchain)
+	return ret
+}
+
+func (a *hostAllocator) DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(buffer)
+	defer done()
+	a.Driver.DestroyBuffer(device, buffer, pAllocator)
+}
+
+func (a *hostAllocator) DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(commandPool)
+	defer done()
+	a.Driver.DestroyCommandPool(device, commandPool, pAllocator)
+}
+
+func (a *hostAllocator) DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(callback)
+	defer done()
+	a.Driver.DestroyDebugReportCallback(instance, callback, pAllocator)
+}
+
+func (a *hostAllocator) DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(device)
+	defer done()
+	a.Driver.DestroyDevice(device, pAllocator)
+}
+
+func (a *hostAllocator) DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(fence)
+	defer done()
+	a.Driver.DestroyFence(device, fence, pAllocator)
+}
+
+func (a *hostAllocator) DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(framebuffer)
+	defer done()
+	a.Driver.DestroyFramebuffer(device, framebuffer, pAllocator)
+}
+
+func (a *hostAllocator) DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(image)
+	defer done()
+	a.Driver.DestroyImage(device, image, pAllocator)
+}
+
+func (a *hostAllocator) DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(imageView)
+	defer done()
+	a.Driver.DestroyImageView(device, imageView, pAllocator)
+}
+
+func (a *hostAllocator) DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(instance)
+	defer done()
+	a.Driver.DestroyInstance(instance, pAllocator)
+}
+
+func (a *hostAllocator) DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(renderPass)
+	defer done()
+	a.Driver.DestroyRenderPass(device, renderPass, pAllocator)
+}
+
+func (a *hostAllocator) DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(semaphore)
+	defer done()
+	a.Driver.DestroySemaphore(device, semaphore, pAllocator)
+}
+
+func (a *hostAllocator) DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(shaderModule)
+	defer done()
+	a.Driver.DestroyShaderModule(device, shaderModule, pAllocator)
+}
+
+func (a *hostAllocator) DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(swapchain)
+	defer done()
+	a.Driver.DestroySwapchain(device, swapchain, pAllocator)
+}
+
+func (a *hostAllocator) FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks) {
+	pAllocator, done := a.destroy(memory)
+	defer done()
+	a.Driver.FreeMemory(device, memory, pAllocator)
+}
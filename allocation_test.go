@@ -0,0 +1,147 @@
+package asche
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestHostAllocatorCallbacks(t *testing.T) {
+	type step struct {
+		op        string // alloc, realloc or free
+		mem       int    // the index of the allocation to reallocate or free, by order of alloc steps
+		size      uint
+		alignment uint
+		scope     vk.SystemAllocationScope
+	}
+	tests := []struct {
+		name  string
+		steps []step
+		want  map[vk.SystemAllocationScope]HostMemory
+	}{
+		{
+			name: "alloc",
+			steps: []step{
+				{op: "alloc", size: 24, alignment: 8, scope: vk.SystemAllocationScopeObject},
+				{op: "alloc", size: 100, alignment: 64, scope: vk.SystemAllocationScopeObject},
+				{op: "alloc", size: 1, alignment: 1, scope: vk.SystemAllocationScopeCommand},
+			},
+			want: map[vk.SystemAllocationScope]HostMemory{
+				vk.SystemAllocationScopeObject:  {Bytes: 124, Count: 2},
+				vk.SystemAllocationScopeCommand: {Bytes: 1, Count: 1},
+			},
+		},
+		{
+			name: "free",
+			steps: []step{
+				{op: "alloc", size: 24, alignment: 8, scope: vk.SystemAllocationScopeObject},
+				{op: "alloc", size: 100, alignment: 256, scope: vk.SystemAllocationScopeCache},
+				{op: "free", mem: 0},
+			},
+			want: map[vk.SystemAllocationScope]HostMemory{
+				vk.SystemAllocationScopeCache: {Bytes: 100, Count: 1},
+			},
+		},
+		{
+			name: "realloc",
+			steps: []step{
+				{op: "alloc", size: 16, alignment: 16, scope: vk.SystemAllocationScopeDevice},
+				{op: "realloc", mem: 0, size: 64, alignment: 16, scope: vk.SystemAllocationScopeDevice},
+				{op: "realloc", mem: 0, size: 8, alignment: 32, scope: vk.SystemAllocationScopeDevice},
+			},
+			want: map[vk.SystemAllocationScope]HostMemory{
+				vk.SystemAllocationScopeDevice: {Bytes: 8, Count: 1},
+			},
+		},
+		{
+			name: "realloc null",
+			steps: []step{
+				{op: "realloc", mem: 0, size: 32, alignment: 8, scope: vk.SystemAllocationScopeInstance},
+			},
+			want: map[vk.SystemAllocationScope]HostMemory{
+				vk.SystemAllocationScopeInstance: {Bytes: 32, Count: 1},
+			},
+		},
+		{
+			name: "realloc to zero",
+			steps: []step{
+				{op: "alloc", size: 32, alignment: 8, scope: vk.SystemAllocationScopeObject},
+				{op: "realloc", mem: 0, size: 0, alignment: 8, scope: vk.SystemAllocationScopeObject},
+			},
+		},
+		{
+			name:  "free null",
+			steps: []step{{op: "free", mem: 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := newHostAllocator(fake.New())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer a.release()
+			pAllocator := callbacks(a.objects)
+
+			var mems []unsafe.Pointer
+			for i, st := range tt.steps {
+				var mem unsafe.Pointer
+				switch st.op {
+				case "alloc":
+					mem = fake.Allocate(pAllocator, st.size, st.alignment, st.scope)
+					mems = append(mems, mem)
+				case "realloc":
+					if st.mem == len(mems) {
+						mems = append(mems, nil)
+					}
+					mem = fake.Reallocate(pAllocator, mems[st.mem], st.size, st.alignment, st.scope)
+					mems[st.mem] = mem
+				case "free":
+					if st.mem < len(mems) {
+						fake.Free(pAllocator, mems[st.mem])
+						mems[st.mem] = nil
+					} else {
+						fake.Free(pAllocator, nil)
+					}
+					continue
+				}
+				if st.size == 0 {
+					if mem != nil {
+						t.Fatalf("step %d: got memory for size 0", i)
+					}
+					continue
+				}
+				if mem == nil {
+					t.Fatalf("step %d: out of memory", i)
+				}
+				if uintptr(mem)%uintptr(st.alignment) != 0 {
+					t.Errorf("step %d: %p is not aligned to %d", i, mem, st.alignment)
+				}
+				// the whole allocation is usable without overwriting the header
+				b := unsafe.Slice((*byte)(mem), st.size)
+				for j := range b {
+					b[j] = 0xff
+				}
+			}
+
+			stats := loadAllocations(a.objects)
+			for scope, m := range stats.Scopes {
+				if want := tt.want[vk.SystemAllocationScope(scope)]; m != want {
+					t.Errorf("scope %d: got %+v, want %+v", scope, m, want)
+				}
+			}
+			for i, mem := range mems {
+				if mem != nil {
+					fake.Free(pAllocator, mem)
+					mems[i] = nil
+				}
+			}
+			stats = loadAllocations(a.objects)
+			if total := stats.Total(); total != (HostMemory{}) {
+				t.Errorf("got %+v after freeing everything", total)
+			}
+		})
+	}
+}
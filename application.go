@@ -1,6 +1,11 @@
 package asche
 
-import vk "github.com/vulkan-go/vulkan"
+import (
+	"time"
+
+	"github.com/vulkan-go/asche/trace"
+	vk "github.com/vulkan-go/vulkan"
+)
 
 type VulkanMode uint32
 
@@ -32,6 +37,17 @@ type Application interface {
 	// ApplicationContextPrepare
 	// ApplicationContextCleanup
 	// ApplicationContextInvalidate
+	// ApplicationShaderWatcher
+	// ApplicationRenderPass
+	// ApplicationDepthStencil
+	// ApplicationMultisample
+	// ApplicationDeviceLost
+	// ApplicationTrace
+	// ApplicationMinAPIVersion
+	// ApplicationExplicitExtensions
+	// ApplicationValidation
+	// ApplicationLeakTracker
+	// ApplicationAllocationStats
 }
 
 type ApplicationSwapchainDimensions interface {
@@ -54,10 +70,83 @@ type ApplicationContextInvalidate interface {
 	VulkanContextInvalidate(imageIdx int) error
 }
 
+// ApplicationShaderWatcher enables shader hot reload, the returned interval
+// defines how often the tracked SPIR-V files are polled for changes.
+type ApplicationShaderWatcher interface {
+	VulkanShaderWatchInterval() time.Duration
+}
+
+// ApplicationRenderPass makes the context own a default render pass and create framebuffers
+// for swapchain images upon every swapchain recreation. Start with DefaultRenderPassOptions.
+type ApplicationRenderPass interface {
+	VulkanRenderPass() *RenderPassOptions
+}
+
+// ApplicationDepthStencil makes the context manage a depth/stencil attachment sized to
+// the swapchain, it's also attached to the default render pass if the latter is enabled.
+type ApplicationDepthStencil interface {
+	VulkanDepthStencil() *DepthStencilOptions
+}
+
+// ApplicationMultisample enables multisampling, the context allocates transient multisampled
+// color and depth/stencil attachments resolving to the swapchain image in the default render pass.
+// The requested sample count is clamped to the counts supported by the device.
+type ApplicationMultisample interface {
+	VulkanSampleCount() vk.SampleCountFlagBits
+}
+
+// ApplicationDeviceLost is notified before every attempt of Platform.RecoverDevice,
+// so it can drop the objects of the lost device, returning an error cancels the recovery.
+// VulkanDeviceRetries limits the number of attempts.
+type ApplicationDeviceLost interface {
+	VulkanDeviceLost(attempt int) error
+	VulkanDeviceRetries() int
+}
+
+// ApplicationTrace writes every Vulkan call made by asche to the returned trace, until the platform
+// is destroyed. The trace is flushed but not closed, read it with the aschetrace command.
+type ApplicationTrace interface {
+	VulkanTrace() *trace.Writer
+}
+
+// ApplicationMinAPIVersion declares the lowest Vulkan version the application works with,
+// NewPlatform fails if the loader or the device doesn't support it.
+type ApplicationMinAPIVersion interface {
+	VulkanMinAPIVersion() vk.Version
+}
+
+// ApplicationExplicitExtensions disables the surface and swapchain extensions added in VulkanPresent mode
+// when VulkanExplicitExtensions returns true, so only the extensions listed by the application are enabled.
+type ApplicationExplicitExtensions interface {
+	VulkanExplicitExtensions() bool
+}
+
+// ApplicationValidation enables DefaultValidationLayer along with the validation features
+// of the config, messages are logged unless ignored by ID. It takes precedence over VulkanDebug.
+type ApplicationValidation interface {
+	VulkanValidation() *ValidationConfig
+}
+
+// ApplicationLeakTracker records the Vulkan objects created through asche along with their
+// creation stacks, Platform.Destroy calls VulkanLeaks with the ones that were not destroyed, if any.
+// Tests can fail from it.
+type ApplicationLeakTracker interface {
+	VulkanLeaks(leaks []Leak)
+}
+
+// ApplicationAllocationStats makes asche pass allocation callbacks to the driver, so the host memory
+// it allocates is reported by Platform.AllocationStats. Objects created through asche must be destroyed
+// through asche too, e.g. with Buffer.Destroy or DestroyShaderModule.
+type ApplicationAllocationStats interface {
+	VulkanAllocationStats() bool
+}
+
 var (
 	DefaultVulkanAppVersion = vk.MakeVersion(1, 0, 0)
 	DefaultVulkanAPIVersion = vk.MakeVersion(1, 0, 0)
 	DefaultVulkanMode       = VulkanCompute | VulkanGraphics | VulkanPresent
+	// DefaultDeviceLostRetries limits device recovery attempts unless set by ApplicationDeviceLost.
+	DefaultDeviceLostRetries = 3
 )
 
 // SwapchainDimensions describes the size and format of the swapchain.
@@ -84,7 +173,7 @@ func (app *BaseVulkanApp) VulkanInit(ctx Context) error {
 }
 
 func (app *BaseVulkanApp) VulkanAPIVersion() vk.Version {
-	return vk.Version(vk.MakeVersion(1, 0, 0))
+	return vk.Version(DefaultVulkanAPIVersion)
 }
 
 func (app *BaseVulkanApp) VulkanAppVersion() vk.Version {
@@ -0,0 +1,108 @@
+// Command aschetrace prints a trace of Vulkan calls recorded with asche.ApplicationTrace.
+//
+//	aschetrace [-func name] [-errors] [-summary] trace.bin
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/vulkan-go/asche/trace"
+)
+
+var (
+	funcFilter = flag.String("func", "", "print only the calls of functions containing the substring")
+	onlyErrors = flag.Bool("errors", false, "print only the failed calls")
+	summary    = flag.Bool("summary", false, "print the number of calls and the total duration per function")
+)
+
+type stat struct {
+	name   string
+	calls  int
+	failed int
+	total  time.Duration
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("aschetrace: ")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: aschetrace [flags] trace")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer f.Close()
+	r, err := trace.NewReader(f)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !*summary {
+		fmt.Println("trace started at", r.Header().Start.Format(time.RFC3339Nano))
+	}
+
+	stats := make(map[string]*stat)
+	for {
+		c, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			log.Println("trace is truncated, the process has probably crashed")
+			break
+		} else if err != nil {
+			log.Fatalln(err)
+		}
+		if !strings.Contains(c.Func, *funcFilter) || (*onlyErrors && !c.Failed()) {
+			continue
+		}
+		if !*summary {
+			fmt.Println(c.String())
+			continue
+		}
+		s, ok := stats[c.Func]
+		if !ok {
+			s = &stat{name: c.Func}
+			stats[c.Func] = s
+		}
+		s.calls++
+		s.total += c.Duration
+		if c.Failed() {
+			s.failed++
+		}
+	}
+	if *summary {
+		printSummary(stats)
+	}
+}
+
+func printSummary(stats map[string]*stat) {
+	list := make([]*stat, 0, len(stats))
+	for _, s := range stats {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].total > list[j].total
+	})
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "function\tcalls\tfailed\ttotal\tavg\t")
+	for _, s := range list {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%v\t%v\t\n", s.name, s.calls, s.failed,
+			s.total, s.total/time.Duration(s.calls))
+	}
+	w.Flush()
+}
@@ -2,7 +2,10 @@ package asche
 
 import (
 	"errors"
+	"log"
+	"sync"
 
+	"github.com/vulkan-go/asche/internal/driver"
 	vk "github.com/vulkan-go/vulkan"
 )
 
@@ -29,13 +32,83 @@ type Context interface {
 	SwapchainDimensions() *SwapchainDimensions
 	// SwapchainImageResources exposes the swapchain initialized image resources.
 	SwapchainImageResources() []*SwapchainImageResources
-	// AcquireNextImage
+	// AcquireNextImage acquires the next swapchain image and submits its pre-recorded command buffer,
+	// see SwapchainImageResources.CommandBuffer. It's the pre-recorded counterpart of BeginFrame.
 	AcquireNextImage() (imageIndex int, outdated bool, err error)
-	// PresentImage
+	// PresentImage presents the swapchain image once its command buffer has completed.
+	// If the swapchain is out of date or suboptimal, it's re-created at the next frame.
 	PresentImage(imageIdx int) (outdated bool, err error)
+	// Suspended reports whether rendering is paused because the surface has zero area,
+	// e.g. the window is minimized, or there is no surface attached to the platform.
+	// AcquireNextImage and BeginFrame report outdated meanwhile, the application should
+	// wait for window events instead of spinning. Rendering resumes with a fresh swapchain
+	// once the surface extent is non-zero again.
+	Suspended() bool
+	// Resize requests the swapchain to be re-created with the given size at the next frame.
+	// The size is clamped to the surface limits and only applies if the surface extent
+	// is determined by the swapchain, otherwise the swapchain matches the surface.
+	Resize(width, height uint32)
+	// BeginFrame acquires the next swapchain image and returns a frame with a freshly
+	// reset command buffer of the current frame slot, already begun. If the swapchain was
+	// out of date it has been re-created, frame is nil and the application should retry.
+	BeginFrame() (frame *Frame, outdated bool, err error)
+	// EndFrame ends the command buffer of the frame, submits it and presents the image.
+	EndFrame(frame *Frame) (outdated bool, err error)
+	// BeginRenderPass begins the default render pass on the frame command buffer,
+	// using the framebuffer of the acquired image and the clear values of the render pass.
+	BeginRenderPass(frame *Frame, contents vk.SubpassContents)
+	// SecondaryCommandBuffer gets a begun secondary command buffer from the command pool
+	// of the frame slot and the worker, so workers can record concurrently. A worker index
+	// must be used by one goroutine at a time, as recording into buffers of the same pool is not
+	// thread-safe. It inherits the default render pass and the framebuffer of the frame,
+	// if the render pass is enabled.
+	SecondaryCommandBuffer(frame *Frame, worker int) (vk.CommandBuffer, error)
+	// RecordParallel records each chunk into a secondary command buffer in its own goroutine,
+	// using the chunk index as worker, then executes them from the frame command buffer in order.
+	// The errors of the chunks are joined once all of them are done, panics of the chunks
+	// are recovered into errors carrying the stack of the worker.
+	// The render pass must have been begun with vk.SubpassContentsSecondaryCommandBuffers.
+	RecordParallel(frame *Frame, chunks ...func(cmd vk.CommandBuffer) error) error
+	// Submit records commands with the record function into a one-shot command buffer,
+	// submits it to the queue (graphics or present queue of the platform) and waits for completion.
+	// Command buffers and fences are pooled, so it can be used from any goroutine.
+	Submit(queue vk.Queue, record func(cmd vk.CommandBuffer) error) error
+	// SubmitAsync is like Submit, but doesn't wait for completion. The submission completes
+	// in the background, use its Done channel to select on completion or Wait for it.
+	SubmitAsync(queue vk.Queue, record func(cmd vk.CommandBuffer) error) (*Submission, error)
+	// DeferDestroy destroys the objects once all the frames and submissions made so far have completed,
+	// checked when a frame begins. Pending objects are destroyed upon context cleanup anyway.
+	DeferDestroy(objs ...Destroyer)
+	// DeferFunc runs fn once all the frames and submissions made so far have completed,
+	// checked when a frame begins. Pending functions run upon context cleanup anyway.
+	DeferFunc(fn func())
+	// SetObjectName names a Vulkan object for validation messages and captures, e.g. vk.Buffer,
+	// vk.Image or *Buffer and *Image. Objects created by the context are named automatically.
+	// It's a no-op unless VK_EXT_debug_utils is enabled.
+	SetObjectName(object interface{}, name string)
+	// BeginLabel opens a labeled region of the command buffer, the returned func closes it.
+	// It's a no-op unless VK_EXT_debug_utils is enabled.
+	BeginLabel(cmd vk.CommandBuffer, name string) (end func())
+	// InsertLabel inserts a single label into the command buffer.
+	// It's a no-op unless VK_EXT_debug_utils is enabled.
+	InsertLabel(cmd vk.CommandBuffer, name string)
+	// ShaderWatcher gets the shader hot reload watcher, it's nil unless enabled by the application.
+	ShaderWatcher() *ShaderWatcher
+	// RenderPass gets the default render pass, it's null unless enabled by the application.
+	// Swapchain image framebuffers are created for this render pass.
+	RenderPass() vk.RenderPass
+	// ClearValues gets the clear values for attachments of the default render pass.
+	ClearValues() []vk.ClearValue
+	// DepthStencil gets the depth/stencil attachment sized to the swapchain, it's nil unless
+	// enabled by the application. The image is re-created upon swapchain recreation.
+	DepthStencil() *Image
+	// SampleCount gets the sample count of the color and depth/stencil attachments
+	// of the default render pass, pipelines must use it as rasterization sample count.
+	SampleCount() vk.SampleCountFlagBits
 }
 
 type context struct {
+	vkd      driver.Driver
 	platform Platform
 	device   vk.Device
 
@@ -56,7 +129,42 @@ type context struct {
 	drawCompleteSemaphores   []vk.Semaphore
 	imageOwnershipSemaphores []vk.Semaphore
 
-	frameIndex int
+	requestedDimensions *SwapchainDimensions
+	recreate            bool
+	suspended           bool
+	prepared            bool
+
+	frameIndex   int
+	frameCmdPool vk.CommandPool
+	frameCmds    []vk.CommandBuffer
+	frameFences  []vk.Fence
+	frameSerials []uint64
+	frame        *Frame
+
+	deletions   deletionQueue
+	commandSets commandSets
+	workerPools workerPools
+
+	fenceWaiter     *fenceWaiter
+	fenceWaiterOnce sync.Once
+
+	shaderWatcher *ShaderWatcher
+
+	renderPassOptions *RenderPassOptions
+	renderPass        vk.RenderPass
+	renderPassFormat  vk.Format
+	clearValues       []vk.ClearValue
+
+	depthOptions *DepthStencilOptions
+	depthFormat  vk.Format
+	depthImage   *Image
+
+	sampleCount vk.SampleCountFlagBits
+	colorImage  *Image
+
+	// validation reports validation errors in strict mode, it's nil unless enabled
+	validation *validation
+	debugUtils bool
 }
 
 func (c *context) preparePresent() {
@@ -69,15 +177,19 @@ func (c *context) preparePresent() {
 	c.drawCompleteSemaphores = make([]vk.Semaphore, c.frameLag)
 	c.imageOwnershipSemaphores = make([]vk.Semaphore, c.frameLag)
 	for i := 0; i < c.frameLag; i++ {
-		ret := vk.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.imageAcquiredSemaphores[i])
+		ret := c.vkd.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.imageAcquiredSemaphores[i])
 		orPanic(NewError(ret))
-		ret = vk.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.drawCompleteSemaphores[i])
+		ret = c.vkd.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.drawCompleteSemaphores[i])
 		orPanic(NewError(ret))
 		if c.platform.HasSeparatePresentQueue() {
-			ret = vk.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.imageOwnershipSemaphores[i])
+			ret = c.vkd.CreateSemaphore(c.device, semaphoreCreateInfo, nil, &c.imageOwnershipSemaphores[i])
 			orPanic(NewError(ret))
+			c.nameObject(c.imageOwnershipSemaphores[i], "image ownership semaphore %d", i)
 		}
+		c.nameObject(c.imageAcquiredSemaphores[i], "image acquired semaphore %d", i)
+		c.nameObject(c.drawCompleteSemaphores[i], "draw complete semaphore %d", i)
 	}
+	c.prepareFrames()
 }
 
 func (c *context) destroy() {
@@ -88,29 +200,58 @@ func (c *context) destroy() {
 		}
 		return
 	}()
+	c.deletions.flush()
+	if c.shaderWatcher != nil {
+		c.shaderWatcher.destroy()
+		c.shaderWatcher = nil
+	}
 
-	for i := 0; i < c.frameLag; i++ {
-		vk.DestroySemaphore(c.device, c.imageAcquiredSemaphores[i], nil)
-		vk.DestroySemaphore(c.device, c.drawCompleteSemaphores[i], nil)
+	c.destroyFrames()
+	for i := 0; i < len(c.imageAcquiredSemaphores); i++ {
+		c.vkd.DestroySemaphore(c.device, c.imageAcquiredSemaphores[i], nil)
+		c.vkd.DestroySemaphore(c.device, c.drawCompleteSemaphores[i], nil)
 		if c.platform.HasSeparatePresentQueue() {
-			vk.DestroySemaphore(c.device, c.imageOwnershipSemaphores[i], nil)
+			c.vkd.DestroySemaphore(c.device, c.imageOwnershipSemaphores[i], nil)
 		}
 	}
 	for i := 0; i < len(c.swapchainImageResources); i++ {
-		c.swapchainImageResources[i].Destroy(c.device, c.cmdPool)
+		c.swapchainImageResources[i].Destroy(c.device, c.cmdPools()...)
 	}
 	c.swapchainImageResources = nil
+	if c.depthImage != nil {
+		c.depthImage.Destroy()
+		c.depthImage = nil
+	}
+	if c.colorImage != nil {
+		c.colorImage.Destroy()
+		c.colorImage = nil
+	}
+	c.destroyRenderPass()
 	if c.swapchain != vk.NullSwapchain {
-		vk.DestroySwapchain(c.device, c.swapchain, nil)
+		c.vkd.DestroySwapchain(c.device, c.swapchain, nil)
 		c.swapchain = vk.NullSwapchain
 	}
-	vk.DestroyCommandPool(c.device, c.cmdPool, nil)
+	if c.fenceWaiter != nil {
+		c.fenceWaiter.destroy()
+		c.fenceWaiter = nil
+	}
+	c.commandSets.destroy(c.device)
+	c.workerPools.destroy(c.device)
+	c.vkd.DestroyCommandPool(c.device, c.cmdPool, nil)
 	if c.platform.HasSeparatePresentQueue() {
-		vk.DestroyCommandPool(c.device, c.presentCmdPool, nil)
+		c.vkd.DestroyCommandPool(c.device, c.presentCmdPool, nil)
 	}
 	c.platform = nil
 }
 
+// cmdPools gets the pools of the swapchain image command buffers, see SwapchainImageResources.Destroy.
+func (c *context) cmdPools() []vk.CommandPool {
+	if c.platform.HasSeparatePresentQueue() {
+		return []vk.CommandPool{c.cmdPool, c.presentCmdPool}
+	}
+	return []vk.CommandPool{c.cmdPool}
+}
+
 func (c *context) Device() vk.Device {
 	return c.device
 }
@@ -131,6 +272,26 @@ func (c *context) SwapchainImageResources() []*SwapchainImageResources {
 	return c.swapchainImageResources
 }
 
+func (c *context) ShaderWatcher() *ShaderWatcher {
+	return c.shaderWatcher
+}
+
+func (c *context) RenderPass() vk.RenderPass {
+	return c.renderPass
+}
+
+func (c *context) ClearValues() []vk.ClearValue {
+	return c.clearValues
+}
+
+func (c *context) DepthStencil() *Image {
+	return c.depthImage
+}
+
+func (c *context) SampleCount() vk.SampleCountFlagBits {
+	return c.sampleCount
+}
+
 func (c *context) SetOnPrepare(onPrepare func() error) {
 	c.onPrepare = onPrepare
 }
@@ -144,29 +305,31 @@ func (c *context) SetOnInvalidate(onInvalidate func(imageIdx int) error) {
 }
 
 func (c *context) prepare(needCleanup bool) {
-	vk.DeviceWaitIdle(c.device)
+	c.platform.DeviceWaitIdle()
 
 	if needCleanup {
 		if c.onCleanup != nil {
 			orPanic(c.onCleanup())
 		}
 
-		vk.DestroyCommandPool(c.device, c.cmdPool, nil)
+		c.vkd.DestroyCommandPool(c.device, c.cmdPool, nil)
 		if c.platform.HasSeparatePresentQueue() {
-			vk.DestroyCommandPool(c.device, c.presentCmdPool, nil)
+			c.vkd.DestroyCommandPool(c.device, c.presentCmdPool, nil)
 		}
 	}
+	c.deletions.flush()
 
 	var cmdPool vk.CommandPool
-	ret := vk.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
+	ret := c.vkd.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
 		SType:            vk.StructureTypeCommandPoolCreateInfo,
 		QueueFamilyIndex: c.platform.GraphicsQueueFamilyIndex(),
 	}, nil, &cmdPool)
 	orPanic(NewError(ret))
 	c.cmdPool = cmdPool
+	c.nameObject(c.cmdPool, "command pool")
 
 	var cmd = make([]vk.CommandBuffer, 1)
-	ret = vk.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
+	ret = c.vkd.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
 		SType:              vk.StructureTypeCommandBufferAllocateInfo,
 		CommandPool:        c.cmdPool,
 		Level:              vk.CommandBufferLevelPrimary,
@@ -174,15 +337,16 @@ func (c *context) prepare(needCleanup bool) {
 	}, cmd)
 	orPanic(NewError(ret))
 	c.cmd = cmd[0]
+	c.nameObject(c.cmd, "init command buffer")
 
-	ret = vk.BeginCommandBuffer(c.cmd, &vk.CommandBufferBeginInfo{
+	ret = c.vkd.BeginCommandBuffer(c.cmd, &vk.CommandBufferBeginInfo{
 		SType: vk.StructureTypeCommandBufferBeginInfo,
 	})
 	orPanic(NewError(ret))
 
 	for i := 0; i < len(c.swapchainImageResources); i++ {
 		var cmd = make([]vk.CommandBuffer, 1)
-		vk.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
+		c.vkd.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
 			SType:              vk.StructureTypeCommandBufferAllocateInfo,
 			CommandPool:        c.cmdPool,
 			Level:              vk.CommandBufferLevelPrimary,
@@ -190,20 +354,22 @@ func (c *context) prepare(needCleanup bool) {
 		}, cmd)
 		orPanic(NewError(ret))
 		c.swapchainImageResources[i].cmd = cmd[0]
+		c.nameObject(cmd[0], "draw command buffer %d", i)
 	}
 
 	if c.platform.HasSeparatePresentQueue() {
 		var cmdPool vk.CommandPool
-		ret = vk.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
+		ret = c.vkd.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
 			SType:            vk.StructureTypeCommandPoolCreateInfo,
 			QueueFamilyIndex: c.platform.PresentQueueFamilyIndex(),
 		}, nil, &cmdPool)
 		orPanic(NewError(ret))
 		c.presentCmdPool = cmdPool
+		c.nameObject(c.presentCmdPool, "present command pool")
 
 		for i := 0; i < len(c.swapchainImageResources); i++ {
 			var cmd = make([]vk.CommandBuffer, 1)
-			ret = vk.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
+			ret = c.vkd.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
 				SType:              vk.StructureTypeCommandBufferAllocateInfo,
 				CommandPool:        c.presentCmdPool,
 				Level:              vk.CommandBufferLevelPrimary,
@@ -211,6 +377,7 @@ func (c *context) prepare(needCleanup bool) {
 			}, cmd)
 			orPanic(NewError(ret))
 			c.swapchainImageResources[i].graphicsToPresentCmd = cmd[0]
+			c.nameObject(cmd[0], "ownership command buffer %d", i)
 
 			c.swapchainImageResources[i].SetImageOwnership(
 				c.platform.GraphicsQueueFamilyIndex(), c.platform.PresentQueueFamilyIndex())
@@ -219,7 +386,7 @@ func (c *context) prepare(needCleanup bool) {
 
 	for i := 0; i < len(c.swapchainImageResources); i++ {
 		var view vk.ImageView
-		ret = vk.CreateImageView(c.device, &vk.ImageViewCreateInfo{
+		ret = c.vkd.CreateImageView(c.device, &vk.ImageViewCreateInfo{
 			SType:  vk.StructureTypeImageViewCreateInfo,
 			Format: c.swapchainDimensions.Format,
 			Components: vk.ComponentMapping{
@@ -238,55 +405,72 @@ func (c *context) prepare(needCleanup bool) {
 		}, nil, &view)
 		orPanic(NewError(ret))
 		c.swapchainImageResources[i].view = view
+		c.nameObject(view, "swapchain image view %d", i)
+	}
+
+	if c.sampleCount > vk.SampleCount1Bit {
+		c.prepareColorTarget()
+	}
+	if c.depthOptions != nil {
+		c.prepareDepthStencil()
+	}
+	if c.renderPassOptions != nil {
+		c.prepareRenderPass()
+		c.prepareFramebuffers()
 	}
 
 	if c.onPrepare != nil {
 		orPanic(c.onPrepare())
 	}
 	c.flushInitCmd()
+	c.prepared = true
 }
 
 func (c *context) flushInitCmd() {
 	if c.cmd == nil {
 		return
 	}
-	ret := vk.EndCommandBuffer(c.cmd)
+	ret := c.vkd.EndCommandBuffer(c.cmd)
 	orPanic(NewError(ret))
 
 	var fence vk.Fence
-	ret = vk.CreateFence(c.device, &vk.FenceCreateInfo{
+	ret = c.vkd.CreateFence(c.device, &vk.FenceCreateInfo{
 		SType: vk.StructureTypeFenceCreateInfo,
 	}, nil, &fence)
 	orPanic(NewError(ret))
 
 	cmdBufs := []vk.CommandBuffer{c.cmd}
-	ret = vk.QueueSubmit(c.platform.GraphicsQueue(), 1, []vk.SubmitInfo{{
+	err := c.platform.SyncGraphicsQueue().Submit([]vk.SubmitInfo{{
 		SType:              vk.StructureTypeSubmitInfo,
 		CommandBufferCount: 1,
 		PCommandBuffers:    cmdBufs,
 	}}, fence)
-	orPanic(NewError(ret))
+	orPanic(err, func() {
+		c.vkd.DestroyFence(c.device, fence, nil)
+	})
 
-	ret = vk.WaitForFences(c.device, 1, []vk.Fence{fence}, vk.True, vk.MaxUint64)
+	ret = c.vkd.WaitForFences(c.device, 1, []vk.Fence{fence}, vk.True, vk.MaxUint64)
 	orPanic(NewError(ret))
 
-	vk.FreeCommandBuffers(c.device, c.cmdPool, 1, cmdBufs)
-	vk.DestroyFence(c.device, fence, nil)
+	c.vkd.FreeCommandBuffers(c.device, c.cmdPool, 1, cmdBufs)
+	c.vkd.DestroyFence(c.device, fence, nil)
 	c.cmd = nil
 }
 
-func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, dimensions *SwapchainDimensions) {
+// prepareSwapchain (re)creates the swapchain for the surface, unless the surface has zero area
+// (e.g. the window is minimized) and a swapchain cannot be created, reporting false then.
+func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, dimensions *SwapchainDimensions) bool {
 	// Read surface capabilities
 	var surfaceCapabilities vk.SurfaceCapabilities
-	ret := vk.GetPhysicalDeviceSurfaceCapabilities(gpu, surface, &surfaceCapabilities)
+	ret := c.vkd.GetPhysicalDeviceSurfaceCapabilities(gpu, surface, &surfaceCapabilities)
 	orPanic(NewError(ret))
 	surfaceCapabilities.Deref()
 
 	// Get available surface pixel formats
 	var formatCount uint32
-	vk.GetPhysicalDeviceSurfaceFormats(gpu, surface, &formatCount, nil)
+	c.vkd.GetPhysicalDeviceSurfaceFormats(gpu, surface, &formatCount, nil)
 	formats := make([]vk.SurfaceFormat, formatCount)
-	vk.GetPhysicalDeviceSurfaceFormats(gpu, surface, &formatCount, formats)
+	c.vkd.GetPhysicalDeviceSurfaceFormats(gpu, surface, &formatCount, formats)
 
 	// Select a proper surface format
 	var format vk.SurfaceFormat
@@ -310,11 +494,19 @@ func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, di
 	var swapchainSize vk.Extent2D
 	surfaceCapabilities.CurrentExtent.Deref()
 	if surfaceCapabilities.CurrentExtent.Width == vk.MaxUint32 {
-		swapchainSize.Width = dimensions.Width
-		swapchainSize.Height = dimensions.Height
+		// The surface size is determined by the swapchain extent.
+		surfaceCapabilities.MinImageExtent.Deref()
+		surfaceCapabilities.MaxImageExtent.Deref()
+		swapchainSize.Width = clampUint32(dimensions.Width,
+			surfaceCapabilities.MinImageExtent.Width, surfaceCapabilities.MaxImageExtent.Width)
+		swapchainSize.Height = clampUint32(dimensions.Height,
+			surfaceCapabilities.MinImageExtent.Height, surfaceCapabilities.MaxImageExtent.Height)
 	} else {
 		swapchainSize = surfaceCapabilities.CurrentExtent
 	}
+	if swapchainSize.Width == 0 || swapchainSize.Height == 0 {
+		return false
+	}
 	// The FIFO present mode is guaranteed by the spec to be supported
 	// and to have no tearing.  It's a great default present mode to use.
 	swapchainPresentMode := vk.PresentModeFifo
@@ -358,7 +550,7 @@ func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, di
 	// Create a swapchain
 	var swapchain vk.Swapchain
 	oldSwapchain := c.swapchain
-	ret = vk.CreateSwapchain(c.device, &vk.SwapchainCreateInfo{
+	ret = c.vkd.CreateSwapchain(c.device, &vk.SwapchainCreateInfo{
 		SType:           vk.StructureTypeSwapchainCreateInfo,
 		Surface:         surface,
 		MinImageCount:   desiredSwapchainImages, // 1 - 3?
@@ -379,9 +571,10 @@ func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, di
 	}, nil, &swapchain)
 	orPanic(NewError(ret))
 	if oldSwapchain != vk.NullSwapchain {
-		vk.DestroySwapchain(c.device, oldSwapchain, nil)
+		c.vkd.DestroySwapchain(c.device, oldSwapchain, nil)
 	}
 	c.swapchain = swapchain
+	c.nameObject(c.swapchain, "swapchain")
 
 	c.swapchainDimensions = &SwapchainDimensions{
 		Width:  swapchainSize.Width,
@@ -390,50 +583,165 @@ func (c *context) prepareSwapchain(gpu vk.PhysicalDevice, surface vk.Surface, di
 	}
 
 	var imageCount uint32
-	ret = vk.GetSwapchainImages(c.device, c.swapchain, &imageCount, nil)
+	ret = c.vkd.GetSwapchainImages(c.device, c.swapchain, &imageCount, nil)
 	orPanic(NewError(ret))
 	swapchainImages := make([]vk.Image, imageCount)
-	ret = vk.GetSwapchainImages(c.device, c.swapchain, &imageCount, swapchainImages)
+	ret = c.vkd.GetSwapchainImages(c.device, c.swapchain, &imageCount, swapchainImages)
 	orPanic(NewError(ret))
 	for i := 0; i < len(c.swapchainImageResources); i++ {
-		c.swapchainImageResources[i].Destroy(c.device, c.cmdPool)
+		c.swapchainImageResources[i].Destroy(c.device, c.cmdPools()...)
 	}
 	c.swapchainImageResources = make([]*SwapchainImageResources, 0, imageCount)
 	for i := 0; i < len(swapchainImages); i++ {
 		c.swapchainImageResources = append(c.swapchainImageResources, &SwapchainImageResources{
+			vkd:   c.vkd,
 			image: swapchainImages[i],
 		})
+		c.nameObject(swapchainImages[i], "swapchain image %d", i)
 	}
+	return true
 }
 
 func (c *context) AcquireNextImage() (imageIndex int, outdated bool, err error) {
+	defer c.checkValidation(&err)
 	defer checkErr(&err)
 
+	imageIndex, outdated = c.acquire()
+	if outdated {
+		return
+	}
+	c.submit(imageIndex, c.swapchainImageResources[imageIndex].cmd)
+	return
+}
+
+// acquire waits until the current frame slot is no longer in use by the device
+// and acquires the next swapchain image. A swapchain recreation scheduled
+// by Resize or a previous suboptimal result happens here, as well as the one
+// required by an out of date swapchain, reporting it as outdated.
+// While suspended, it checks whether the surface has a non-zero extent again.
+func (c *context) acquire() (imageIndex int, outdated bool) {
+	if c.shaderWatcher != nil {
+		orPanic(c.shaderWatcher.apply())
+	}
+	if c.recreate || c.suspended {
+		if c.platform.Surface() == vk.NullSurface {
+			// detached, waiting for AttachSurface
+			return 0, true
+		}
+		c.recreateSwapchain()
+		return 0, true
+	}
+	ret := c.vkd.WaitForFences(c.device, 1, []vk.Fence{c.frameFences[c.frameIndex]}, vk.True, vk.MaxUint64)
+	orPanic(NewError(ret))
+	c.deletions.complete(c.frameSerials[c.frameIndex])
+
 	// Get the index of the next available swapchain image
 	var idx uint32
-	ret := vk.AcquireNextImage(c.device, c.swapchain, vk.MaxUint64,
+	ret = c.vkd.AcquireNextImage(c.device, c.swapchain, vk.MaxUint64,
 		c.imageAcquiredSemaphores[c.frameIndex], vk.NullFence, &idx)
+	switch ret {
+	case vk.ErrorOutOfDate:
+		c.recreateSwapchain()
+		return 0, true
+	case vk.ErrorSurfaceLost:
+		c.surfaceLost()
+		return 0, true
+	case vk.Suboptimal:
+		// the image is acquired already, so it must be presented first
+		c.recreate = true
+	case vk.Success:
+	default:
+		orPanic(NewError(ret))
+	}
 	imageIndex = int(idx)
 	if c.onInvalidate != nil {
 		orPanic(c.onInvalidate(imageIndex))
 	}
-	switch ret {
-	case vk.ErrorOutOfDate:
-		c.frameIndex++
-		c.frameIndex = c.frameIndex % c.frameLag
-		c.prepareSwapchain(c.platform.PhysicalDevice(),
-			c.platform.Surface(), c.SwapchainDimensions())
-		c.prepare(true)
-		outdated = true
+	return imageIndex, false
+}
+
+// recreateSwapchain re-creates the swapchain using the requested dimensions
+// and prepares the context again once the device is idle. The context gets
+// suspended if the surface has zero area.
+func (c *context) recreateSwapchain() {
+	c.recreate = false
+	c.platform.DeviceWaitIdle()
+	c.suspended = !c.prepareSwapchain(c.platform.PhysicalDevice(),
+		c.platform.Surface(), c.requestedDimensions)
+	if c.suspended {
 		return
-	case vk.Suboptimal, vk.Success:
-	default:
-		orPanic(NewError(ret))
 	}
+	c.prepare(c.prepared)
+}
+
+// releaseSwapchain destroys the swapchain and the resources sized to it once the device is idle,
+// keeping the render pass, so the swapchain can be created again for a new surface.
+func (c *context) releaseSwapchain() {
+	c.platform.DeviceWaitIdle()
+	c.deletions.flush()
+	c.frame = nil
+	for i := 0; i < len(c.swapchainImageResources); i++ {
+		c.swapchainImageResources[i].Destroy(c.device, c.cmdPools()...)
+	}
+	c.swapchainImageResources = nil
+	if c.depthImage != nil {
+		c.depthImage.Destroy()
+		c.depthImage = nil
+	}
+	if c.colorImage != nil {
+		c.colorImage.Destroy()
+		c.colorImage = nil
+	}
+	if c.swapchain != vk.NullSwapchain {
+		c.vkd.DestroySwapchain(c.device, c.swapchain, nil)
+		c.swapchain = vk.NullSwapchain
+	}
+	c.suspended = true
+}
 
-	graphicsQueue := c.platform.GraphicsQueue()
-	var nullFence vk.Fence
-	ret = vk.QueueSubmit(graphicsQueue, 1, []vk.SubmitInfo{{
+// attachSwapchain creates a swapchain for the surface attached to the platform.
+func (c *context) attachSwapchain() (err error) {
+	defer checkErr(&err)
+	c.recreateSwapchain()
+	return nil
+}
+
+// surfaceLost drops the lost surface and tries to obtain a new one from the application,
+// the context stays suspended until a surface gets attached.
+func (c *context) surfaceLost() {
+	log.Println("vulkan warning: surface lost")
+	c.platform.DetachSurface()
+	if err := c.platform.AttachSurface(); err != nil {
+		log.Println("vulkan warning: failed to attach a new surface:", err)
+	}
+}
+
+func (c *context) Suspended() bool {
+	return c.suspended
+}
+
+func (c *context) Resize(width, height uint32) {
+	if c.requestedDimensions == nil {
+		// no swapchain to resize
+		return
+	}
+	dimensions := *c.requestedDimensions
+	dimensions.Width = width
+	dimensions.Height = height
+	c.requestedDimensions = &dimensions
+	c.recreate = true
+}
+
+// submit submits the command buffer rendering into the acquired swapchain image,
+// signalling the fence of the current frame slot on completion.
+func (c *context) submit(imageIndex int, cmd vk.CommandBuffer) {
+	fence := c.frameFences[c.frameIndex]
+	ret := c.vkd.ResetFences(c.device, 1, []vk.Fence{fence})
+	orPanic(NewError(ret))
+	c.frameSerials[c.frameIndex] = c.deletions.submit()
+
+	graphicsQueue := c.platform.SyncGraphicsQueue()
+	err := graphicsQueue.Submit([]vk.SubmitInfo{{
 		SType: vk.StructureTypeSubmitInfo,
 		PWaitDstStageMask: []vk.PipelineStageFlags{
 			vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
@@ -444,43 +752,66 @@ func (c *context) AcquireNextImage() (imageIndex int, outdated bool, err error)
 		},
 		CommandBufferCount: 1,
 		PCommandBuffers: []vk.CommandBuffer{
-			c.swapchainImageResources[idx].cmd,
+			cmd,
 		},
 		SignalSemaphoreCount: 1,
 		PSignalSemaphores: []vk.Semaphore{
 			c.drawCompleteSemaphores[c.frameIndex],
 		},
-	}}, nullFence)
-	orPanic(NewError(ret))
+	}}, fence)
+	orPanic(err, func() {
+		c.signalFrame(graphicsQueue, fence)
+	})
 
 	if c.platform.HasSeparatePresentQueue() {
-		presentQueue := c.platform.PresentQueue()
+		presentQueue := c.platform.SyncPresentQueue()
 
+		// Transfer the image ownership once drawing is complete.
 		var nullFence vk.Fence
-		ret = vk.QueueSubmit(presentQueue, 1, []vk.SubmitInfo{{
+		err = presentQueue.Submit([]vk.SubmitInfo{{
 			SType: vk.StructureTypeSubmitInfo,
 			PWaitDstStageMask: []vk.PipelineStageFlags{
 				vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
 			},
 			WaitSemaphoreCount: 1,
 			PWaitSemaphores: []vk.Semaphore{
-				c.imageAcquiredSemaphores[c.frameIndex],
+				c.drawCompleteSemaphores[c.frameIndex],
 			},
 			CommandBufferCount: 1,
 			PCommandBuffers: []vk.CommandBuffer{
-				c.swapchainImageResources[idx].graphicsToPresentCmd,
+				c.swapchainImageResources[imageIndex].graphicsToPresentCmd,
 			},
 			SignalSemaphoreCount: 1,
 			PSignalSemaphores: []vk.Semaphore{
 				c.imageOwnershipSemaphores[c.frameIndex],
 			},
 		}}, nullFence)
-		orPanic(NewError(ret))
+		orPanic(err)
+	}
+}
+
+// signalFrame signals the fence of the frame slot after its submission has failed, so the next
+// wait for the slot doesn't hang. The empty submission consumes the image acquired semaphore too,
+// so it can be used by the next acquire. Should it fail as well, the device is lost and waiting
+// for the fence reports that.
+func (c *context) signalFrame(queue *Queue, fence vk.Fence) {
+	err := queue.Submit([]vk.SubmitInfo{{
+		SType: vk.StructureTypeSubmitInfo,
+		PWaitDstStageMask: []vk.PipelineStageFlags{
+			vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+		},
+		WaitSemaphoreCount: 1,
+		PWaitSemaphores: []vk.Semaphore{
+			c.imageAcquiredSemaphores[c.frameIndex],
+		},
+	}}, fence)
+	if err != nil {
+		log.Println("vulkan warning: failed to signal the frame fence:", err)
 	}
-	return
 }
 
 func (c *context) PresentImage(imageIdx int) (outdated bool, err error) {
+	defer c.checkValidation(&err)
 	// If we are using separate queues we have to wait for image ownership,
 	// otherwise wait for draw complete.
 	var semaphore vk.Semaphore
@@ -489,8 +820,8 @@ func (c *context) PresentImage(imageIdx int) (outdated bool, err error) {
 	} else {
 		semaphore = c.drawCompleteSemaphores[c.frameIndex]
 	}
-	presentQueue := c.platform.PresentQueue()
-	ret := vk.QueuePresent(presentQueue, &vk.PresentInfo{
+	presentQueue := c.platform.SyncPresentQueue()
+	ret := presentQueue.Present(&vk.PresentInfo{
 		SType:              vk.StructureTypePresentInfo,
 		WaitSemaphoreCount: 1,
 		PWaitSemaphores:    []vk.Semaphore{semaphore},
@@ -502,10 +833,15 @@ func (c *context) PresentImage(imageIdx int) (outdated bool, err error) {
 	c.frameIndex = c.frameIndex % c.frameLag
 
 	switch ret {
-	case vk.ErrorOutOfDate:
+	case vk.ErrorOutOfDate, vk.Suboptimal:
+		c.recreate = true
+		outdated = true
+		return
+	case vk.ErrorSurfaceLost:
+		c.surfaceLost()
 		outdated = true
 		return
-	case vk.Suboptimal, vk.Success:
+	case vk.Success:
 		return
 	default:
 		err = NewError(ret)
@@ -514,6 +850,7 @@ func (c *context) PresentImage(imageIdx int) (outdated bool, err error) {
 }
 
 type SwapchainImageResources struct {
+	vkd                  driver.Driver
 	image                vk.Image
 	cmd                  vk.CommandBuffer
 	graphicsToPresentCmd vk.CommandBuffer
@@ -526,26 +863,33 @@ type SwapchainImageResources struct {
 	uniformMemory vk.DeviceMemory
 }
 
+// Destroy destroys the resources of the image, the command buffers are freed if their pools are given:
+// the graphics command pool followed by the present command pool, if the present queue is separate.
 func (s *SwapchainImageResources) Destroy(dev vk.Device, cmdPool ...vk.CommandPool) {
-	vk.DestroyFramebuffer(dev, s.framebuffer, nil)
-	vk.DestroyImageView(dev, s.view, nil)
+	s.vkd.DestroyFramebuffer(dev, s.framebuffer, nil)
+	s.vkd.DestroyImageView(dev, s.view, nil)
 	if len(cmdPool) > 0 {
-		vk.FreeCommandBuffers(dev, cmdPool[0], 1, []vk.CommandBuffer{
+		s.vkd.FreeCommandBuffers(dev, cmdPool[0], 1, []vk.CommandBuffer{
 			s.cmd,
 		})
 	}
-	vk.DestroyBuffer(dev, s.uniformBuffer, nil)
-	vk.FreeMemory(dev, s.uniformMemory, nil)
+	if len(cmdPool) > 1 && s.graphicsToPresentCmd != nil {
+		s.vkd.FreeCommandBuffers(dev, cmdPool[1], 1, []vk.CommandBuffer{
+			s.graphicsToPresentCmd,
+		})
+	}
+	s.vkd.DestroyBuffer(dev, s.uniformBuffer, nil)
+	s.vkd.FreeMemory(dev, s.uniformMemory, nil)
 }
 
 func (s *SwapchainImageResources) SetImageOwnership(graphicsQueueFamilyIndex, presentQueueFamilyIndex uint32) {
-	ret := vk.BeginCommandBuffer(s.graphicsToPresentCmd, &vk.CommandBufferBeginInfo{
+	ret := s.vkd.BeginCommandBuffer(s.graphicsToPresentCmd, &vk.CommandBufferBeginInfo{
 		SType: vk.StructureTypeCommandBufferBeginInfo,
 		Flags: vk.CommandBufferUsageFlags(vk.CommandBufferUsageSimultaneousUseBit),
 	})
 	orPanic(NewError(ret))
 
-	vk.CmdPipelineBarrier(s.graphicsToPresentCmd,
+	s.vkd.CmdPipelineBarrier(s.graphicsToPresentCmd,
 		vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
 		vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
 		0, 0, nil, 0, nil, 1, []vk.ImageMemoryBarrier{{
@@ -564,7 +908,7 @@ func (s *SwapchainImageResources) SetImageOwnership(graphicsQueueFamilyIndex, pr
 			},
 		}})
 
-	ret = vk.EndCommandBuffer(s.graphicsToPresentCmd)
+	ret = s.vkd.EndCommandBuffer(s.graphicsToPresentCmd)
 	orPanic(NewError(ret))
 }
 
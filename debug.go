@@ -0,0 +1,162 @@
+package asche
+
+import (
+	"fmt"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// objectHandle gets the object type and the handle of a Vulkan object for VK_EXT_debug_utils.
+func objectHandle(object interface{}) (vk.ObjectType, uint64, bool) {
+	var objectType vk.ObjectType
+	switch h := object.(type) {
+	// dispatchable handles are pointers on all platforms
+	case vk.Instance:
+		return dispatchableHandle(vk.ObjectTypeInstance, unsafe.Pointer(h))
+	case vk.PhysicalDevice:
+		return dispatchableHandle(vk.ObjectTypePhysicalDevice, unsafe.Pointer(h))
+	case vk.Device:
+		return dispatchableHandle(vk.ObjectTypeDevice, unsafe.Pointer(h))
+	case vk.Queue:
+		return dispatchableHandle(vk.ObjectTypeQueue, unsafe.Pointer(h))
+	case vk.CommandBuffer:
+		return dispatchableHandle(vk.ObjectTypeCommandBuffer, unsafe.Pointer(h))
+	// non-dispatchable handles are pointers on 64-bit platforms and uint64 on 32-bit ones
+	case vk.Semaphore:
+		objectType = vk.ObjectTypeSemaphore
+	case vk.Fence:
+		objectType = vk.ObjectTypeFence
+	case vk.DeviceMemory:
+		objectType = vk.ObjectTypeDeviceMemory
+	case vk.Buffer:
+		objectType = vk.ObjectTypeBuffer
+	case vk.Image:
+		objectType = vk.ObjectTypeImage
+	case vk.Event:
+		objectType = vk.ObjectTypeEvent
+	case vk.QueryPool:
+		objectType = vk.ObjectTypeQueryPool
+	case vk.BufferView:
+		objectType = vk.ObjectTypeBufferView
+	case vk.ImageView:
+		objectType = vk.ObjectTypeImageView
+	case vk.ShaderModule:
+		objectType = vk.ObjectTypeShaderModule
+	case vk.PipelineCache:
+		objectType = vk.ObjectTypePipelineCache
+	case vk.PipelineLayout:
+		objectType = vk.ObjectTypePipelineLayout
+	case vk.RenderPass:
+		objectType = vk.ObjectTypeRenderPass
+	case vk.Pipeline:
+		objectType = vk.ObjectTypePipeline
+	case vk.DescriptorSetLayout:
+		objectType = vk.ObjectTypeDescriptorSetLayout
+	case vk.Sampler:
+		objectType = vk.ObjectTypeSampler
+	case vk.DescriptorPool:
+		objectType = vk.ObjectTypeDescriptorPool
+	case vk.DescriptorSet:
+		objectType = vk.ObjectTypeDescriptorSet
+	case vk.Framebuffer:
+		objectType = vk.ObjectTypeFramebuffer
+	case vk.CommandPool:
+		objectType = vk.ObjectTypeCommandPool
+	case vk.Surface:
+		objectType = vk.ObjectTypeSurface
+	case vk.Swapchain:
+		objectType = vk.ObjectTypeSwapchain
+	default:
+		return vk.ObjectTypeUnknown, 0, false
+	}
+	key, ok := handleKey(object)
+	return objectType, key.handle, ok
+}
+
+func dispatchableHandle(objectType vk.ObjectType, ptr unsafe.Pointer) (vk.ObjectType, uint64, bool) {
+	return objectType, uint64(uintptr(ptr)), ptr != nil
+}
+
+// setObjectName names the object if VK_EXT_debug_utils is enabled, *Buffer and *Image
+// get their memory and view named too. Unknown objects are ignored.
+func setObjectName(device vk.Device, enabled bool, object interface{}, name string) {
+	if !enabled {
+		return
+	}
+	switch obj := object.(type) {
+	case *Buffer:
+		setObjectName(device, enabled, obj.Buffer, name)
+		setObjectName(device, enabled, obj.Memory, name+" memory")
+		return
+	case *Image:
+		setObjectName(device, enabled, obj.Image, name)
+		setObjectName(device, enabled, obj.View, name+" view")
+		setObjectName(device, enabled, obj.Memory, name+" memory")
+		return
+	}
+	objectType, handle, ok := objectHandle(object)
+	if !ok {
+		return
+	}
+	// naming is best effort, a failure doesn't affect rendering
+	deviceDriver(device).SetDebugUtilsObjectName(device, &vk.DebugUtilsObjectNameInfo{
+		SType:        vk.StructureTypeDebugUtilsObjectNameInfo,
+		ObjectType:   objectType,
+		ObjectHandle: handle,
+		PObjectName:  safeString(name),
+	})
+}
+
+func (c *context) SetObjectName(object interface{}, name string) {
+	setObjectName(c.device, c.debugUtils, object, name)
+}
+
+// nameObject names an object created by the context.
+func (c *context) nameObject(object interface{}, format string, args ...interface{}) {
+	if c.debugUtils {
+		setObjectName(c.device, true, object, fmt.Sprintf(format, args...))
+	}
+}
+
+func (c *context) BeginLabel(cmd vk.CommandBuffer, name string) (end func()) {
+	if !c.debugUtils {
+		return func() {}
+	}
+	c.vkd.CmdBeginDebugUtilsLabel(cmd, &vk.DebugUtilsLabel{
+		SType:      vk.StructureTypeDebugUtilsLabel,
+		PLabelName: safeString(name),
+	})
+	return func() {
+		c.vkd.CmdEndDebugUtilsLabel(cmd)
+	}
+}
+
+func (c *context) InsertLabel(cmd vk.CommandBuffer, name string) {
+	if !c.debugUtils {
+		return
+	}
+	c.vkd.CmdInsertDebugUtilsLabel(cmd, &vk.DebugUtilsLabel{
+		SType:      vk.StructureTypeDebugUtilsLabel,
+		PLabelName: safeString(name),
+	})
+}
+
+// BeginLabel opens a labeled region of the queue, the returned func closes it.
+// It's a no-op unless VK_EXT_debug_utils is enabled.
+func (q *Queue) BeginLabel(name string) (end func()) {
+	if !q.debugUtils {
+		return func() {}
+	}
+	q.mu.Lock()
+	q.vkd.QueueBeginDebugUtilsLabel(q.queue, &vk.DebugUtilsLabel{
+		SType:      vk.StructureTypeDebugUtilsLabel,
+		PLabelName: safeString(name),
+	})
+	q.mu.Unlock()
+	return func() {
+		q.mu.Lock()
+		q.vkd.QueueEndDebugUtilsLabel(q.queue)
+		q.mu.Unlock()
+	}
+}
@@ -0,0 +1,102 @@
+package asche
+
+import (
+	"sync"
+)
+
+// Destroyer is an object that can be destroyed, like Buffer or Image.
+type Destroyer interface {
+	Destroy()
+}
+
+type deferredDestroy struct {
+	serial uint64
+	fn     func()
+}
+
+// deletionQueue holds destroy functions until the frames and submissions made before
+// they were enqueued have completed. Work is identified by submission serials, which
+// may complete out of order since submissions go to any queue of the platform.
+type deletionQueue struct {
+	mu        sync.Mutex
+	submitted uint64
+	inFlight  map[uint64]bool
+	pending   []deferredDestroy
+}
+
+func (q *deletionQueue) push(fn func()) {
+	q.mu.Lock()
+	q.pending = append(q.pending, deferredDestroy{
+		serial: q.submitted,
+		fn:     fn,
+	})
+	q.mu.Unlock()
+}
+
+// submit allocates a serial for a frame or a submission being submitted.
+func (q *deletionQueue) submit() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.submitted++
+	if q.inFlight == nil {
+		q.inFlight = make(map[uint64]bool)
+	}
+	q.inFlight[q.submitted] = true
+	return q.submitted
+}
+
+// retire marks the work with the serial completed, the destroy functions
+// no longer needed run upon the next complete or flush.
+func (q *deletionQueue) retire(serial uint64) {
+	q.mu.Lock()
+	delete(q.inFlight, serial)
+	q.mu.Unlock()
+}
+
+// complete marks the work with the serial completed, running the destroy functions
+// that are no longer needed.
+func (q *deletionQueue) complete(serial uint64) {
+	q.mu.Lock()
+	delete(q.inFlight, serial)
+	// everything before the oldest serial in flight has completed
+	oldest := q.submitted + 1
+	for s := range q.inFlight {
+		if s < oldest {
+			oldest = s
+		}
+	}
+	var ready []deferredDestroy
+	n := 0
+	for _, d := range q.pending {
+		if d.serial < oldest {
+			ready = append(ready, d)
+			continue
+		}
+		q.pending[n] = d
+		n++
+	}
+	q.pending = q.pending[:n]
+	q.mu.Unlock()
+
+	for _, d := range ready {
+		d.fn()
+	}
+}
+
+// flush runs all the pending destroy functions, the device must be idle.
+func (q *deletionQueue) flush() {
+	q.mu.Lock()
+	q.inFlight = nil
+	q.mu.Unlock()
+	q.complete(0)
+}
+
+func (c *context) DeferDestroy(objs ...Destroyer) {
+	for _, obj := range objs {
+		c.deletions.push(obj.Destroy)
+	}
+}
+
+func (c *context) DeferFunc(fn func()) {
+	c.deletions.push(fn)
+}
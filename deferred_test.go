@@ -0,0 +1,115 @@
+package asche
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeletionQueue(t *testing.T) {
+	// the steps refer to serials by the order of their submit steps, starting from 1
+	type step struct {
+		op   string // push, submit, complete, retire or flush
+		arg  int    // the object pushed, or the serial
+		want []int  // the objects destroyed by the step
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			name: "nothing in flight",
+			steps: []step{
+				{op: "push", arg: 1},
+				{op: "complete", arg: 0, want: []int{1}},
+			},
+		},
+		{
+			name: "held until the submission completes",
+			steps: []step{
+				{op: "submit"},
+				{op: "push", arg: 1},
+				{op: "complete", arg: 0},
+				{op: "complete", arg: 1, want: []int{1}},
+			},
+		},
+		{
+			name: "pushed before the submission",
+			steps: []step{
+				{op: "push", arg: 1},
+				{op: "submit"},
+				{op: "complete", arg: 0, want: []int{1}},
+			},
+		},
+		{
+			name: "out of order",
+			steps: []step{
+				{op: "submit"},
+				{op: "push", arg: 1},
+				{op: "submit"},
+				{op: "push", arg: 2},
+				{op: "complete", arg: 2},
+				{op: "complete", arg: 1, want: []int{1, 2}},
+			},
+		},
+		{
+			name: "older submission in flight",
+			steps: []step{
+				{op: "submit"},
+				{op: "submit"},
+				{op: "push", arg: 1},
+				{op: "submit"},
+				{op: "push", arg: 2},
+				{op: "complete", arg: 2},
+				{op: "complete", arg: 3},
+				{op: "complete", arg: 1, want: []int{1, 2}},
+			},
+		},
+		{
+			name: "retired run on the next complete",
+			steps: []step{
+				{op: "submit"},
+				{op: "push", arg: 1},
+				{op: "retire", arg: 1},
+				{op: "complete", arg: 0, want: []int{1}},
+			},
+		},
+		{
+			name: "flush",
+			steps: []step{
+				{op: "submit"},
+				{op: "push", arg: 1},
+				{op: "submit"},
+				{op: "push", arg: 2},
+				{op: "flush", want: []int{1, 2}},
+				{op: "push", arg: 3},
+				{op: "complete", arg: 0, want: []int{3}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q deletionQueue
+			var destroyed []int
+			serials := []uint64{0}
+			for i, st := range tt.steps {
+				destroyed = nil
+				switch st.op {
+				case "push":
+					obj := st.arg
+					q.push(func() { destroyed = append(destroyed, obj) })
+				case "submit":
+					serials = append(serials, q.submit())
+				case "complete":
+					q.complete(serials[st.arg])
+				case "retire":
+					q.retire(serials[st.arg])
+				case "flush":
+					q.flush()
+				}
+				if !reflect.DeepEqual(destroyed, st.want) {
+					t.Fatalf("step %d (%s %d): destroyed %v, want %v", i, st.op, st.arg, destroyed, st.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package asche
+
+import (
+	"errors"
+	"log"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// DepthStencilOptions configures the depth/stencil attachment managed by the context.
+type DepthStencilOptions struct {
+	// Stencil requests a format with a stencil aspect.
+	Stencil bool
+	// Formats overrides the list of candidate formats in order of preference.
+	Formats []vk.Format
+}
+
+var (
+	// DepthFormats lists depth formats in order of preference.
+	DepthFormats = []vk.Format{
+		vk.FormatD32Sfloat,
+		vk.FormatD32SfloatS8Uint,
+		vk.FormatD24UnormS8Uint,
+		vk.FormatD16Unorm,
+	}
+	// DepthStencilFormats lists depth/stencil formats in order of preference.
+	DepthStencilFormats = []vk.Format{
+		vk.FormatD32SfloatS8Uint,
+		vk.FormatD24UnormS8Uint,
+		vk.FormatD16UnormS8Uint,
+	}
+)
+
+// FindDepthFormat picks the first of the candidate formats that can be used as
+// an optimally tiled depth/stencil attachment on the physical device.
+func FindDepthFormat(gpu vk.PhysicalDevice, candidates []vk.Format) (vk.Format, bool) {
+	return findDepthFormat(vkd, gpu, candidates)
+}
+
+func findDepthFormat(d driver.Driver, gpu vk.PhysicalDevice, candidates []vk.Format) (vk.Format, bool) {
+	for _, format := range candidates {
+		var props vk.FormatProperties
+		d.GetPhysicalDeviceFormatProperties(gpu, format, &props)
+		props.Deref()
+		if props.OptimalTilingFeatures&vk.FormatFeatureFlags(vk.FormatFeatureDepthStencilAttachmentBit) != 0 {
+			return format, true
+		}
+	}
+	return vk.FormatUndefined, false
+}
+
+// HasStencil reports whether the depth format has a stencil aspect.
+func HasStencil(format vk.Format) bool {
+	switch format {
+	case vk.FormatD16UnormS8Uint, vk.FormatD24UnormS8Uint, vk.FormatD32SfloatS8Uint:
+		return true
+	default:
+		return false
+	}
+}
+
+func depthAspect(format vk.Format) vk.ImageAspectFlags {
+	if HasStencil(format) {
+		return vk.ImageAspectFlags(vk.ImageAspectDepthBit | vk.ImageAspectStencilBit)
+	}
+	return vk.ImageAspectFlags(vk.ImageAspectDepthBit)
+}
+
+// Image is an image with the device memory backing it and a view covering it.
+type Image struct {
+	// vkd and device for destroy purposes.
+	vkd    driver.Driver
+	device vk.Device
+	// Image is the image object.
+	Image vk.Image
+	// Memory is the device memory backing image object.
+	Memory vk.DeviceMemory
+	// View is the image view of the whole image.
+	View vk.ImageView
+	// Format is the pixel format of the image.
+	Format vk.Format
+}
+
+func (i *Image) Destroy() {
+	i.vkd.DestroyImageView(i.device, i.View, nil)
+	i.vkd.DestroyImage(i.device, i.Image, nil)
+	i.vkd.FreeMemory(i.device, i.Memory, nil)
+	i.device = nil
+}
+
+// createAttachment creates a 2D single-level attachment image with the first memory type matching
+// one of the memory property preferences, falling back to any memory type supported by the image.
+func (c *context) createAttachment(format vk.Format, samples vk.SampleCountFlagBits,
+	usage vk.ImageUsageFlagBits, aspect vk.ImageAspectFlags, memPrefs ...vk.MemoryPropertyFlagBits) *Image {
+
+	var image vk.Image
+	ret := c.vkd.CreateImage(c.device, &vk.ImageCreateInfo{
+		SType:     vk.StructureTypeImageCreateInfo,
+		ImageType: vk.ImageType2d,
+		Format:    format,
+		Extent: vk.Extent3D{
+			Width:  c.swapchainDimensions.Width,
+			Height: c.swapchainDimensions.Height,
+			Depth:  1,
+		},
+		MipLevels:     1,
+		ArrayLayers:   1,
+		Samples:       samples,
+		Tiling:        vk.ImageTilingOptimal,
+		Usage:         vk.ImageUsageFlags(usage),
+		SharingMode:   vk.SharingModeExclusive,
+		InitialLayout: vk.ImageLayoutUndefined,
+	}, nil, &image)
+	orPanic(NewError(ret))
+
+	var memReqs vk.MemoryRequirements
+	c.vkd.GetImageMemoryRequirements(c.device, image, &memReqs)
+	memReqs.Deref()
+
+	memProps := c.platform.MemoryProperties()
+	var memType uint32
+	var found bool
+	for _, pref := range memPrefs {
+		memType, found = FindRequiredMemoryType(memProps,
+			vk.MemoryPropertyFlagBits(memReqs.MemoryTypeBits), pref)
+		if found {
+			break
+		}
+	}
+	if !found {
+		log.Println("vulkan warning: failed to find preferred memory type")
+		// fallback to the first one available
+		for i := uint32(0); i < vk.MaxMemoryTypes; i++ {
+			if memReqs.MemoryTypeBits&(1<<i) != 0 {
+				memType = i
+				break
+			}
+		}
+	}
+
+	var memory vk.DeviceMemory
+	ret = c.vkd.AllocateMemory(c.device, &vk.MemoryAllocateInfo{
+		SType:           vk.StructureTypeMemoryAllocateInfo,
+		AllocationSize:  memReqs.Size,
+		MemoryTypeIndex: memType,
+	}, nil, &memory)
+	orPanic(NewError(ret), func() {
+		c.vkd.DestroyImage(c.device, image, nil)
+	})
+	ret = c.vkd.BindImageMemory(c.device, image, memory, 0)
+	orPanic(NewError(ret), func() {
+		c.vkd.DestroyImage(c.device, image, nil)
+		c.vkd.FreeMemory(c.device, memory, nil)
+	})
+
+	var view vk.ImageView
+	ret = c.vkd.CreateImageView(c.device, &vk.ImageViewCreateInfo{
+		SType:    vk.StructureTypeImageViewCreateInfo,
+		Image:    image,
+		ViewType: vk.ImageViewType2d,
+		Format:   format,
+		SubresourceRange: vk.ImageSubresourceRange{
+			AspectMask: aspect,
+			LevelCount: 1,
+			LayerCount: 1,
+		},
+	}, nil, &view)
+	orPanic(NewError(ret), func() {
+		c.vkd.DestroyImage(c.device, image, nil)
+		c.vkd.FreeMemory(c.device, memory, nil)
+	})
+	return &Image{
+		vkd:    c.vkd,
+		device: c.device,
+		Image:  image,
+		Memory: memory,
+		View:   view,
+		Format: format,
+	}
+}
+
+// transientDepth reports whether the multisampled depth/stencil attachment
+// is never loaded nor stored by the default render pass.
+func (c *context) transientDepth() bool {
+	opts := c.renderPassOptions
+	if c.sampleCount == vk.SampleCount1Bit || opts == nil {
+		return false
+	}
+	return opts.DepthLoadOp != vk.AttachmentLoadOpLoad && opts.StencilLoadOp != vk.AttachmentLoadOpLoad &&
+		opts.DepthStoreOp == vk.AttachmentStoreOpDontCare && opts.StencilStoreOp == vk.AttachmentStoreOpDontCare
+}
+
+// prepareDepthStencil (re)creates the depth/stencil image sized to the swapchain
+// and records its layout transition into the init command buffer.
+func (c *context) prepareDepthStencil() {
+	if c.depthImage != nil {
+		c.depthImage.Destroy()
+		c.depthImage = nil
+	}
+	if c.depthFormat == vk.FormatUndefined {
+		candidates := c.depthOptions.Formats
+		if len(candidates) == 0 {
+			candidates = DepthFormats
+			if c.depthOptions.Stencil {
+				candidates = DepthStencilFormats
+			}
+		}
+		format, ok := findDepthFormat(c.vkd, c.platform.PhysicalDevice(), candidates)
+		if !ok {
+			orPanic(errors.New("vulkan error: no supported depth/stencil format found"))
+		}
+		c.depthFormat = format
+	}
+	aspect := depthAspect(c.depthFormat)
+	if c.transientDepth() {
+		c.depthImage = c.createAttachment(c.depthFormat, c.sampleCount,
+			vk.ImageUsageDepthStencilAttachmentBit|vk.ImageUsageTransientAttachmentBit, aspect,
+			vk.MemoryPropertyLazilyAllocatedBit, vk.MemoryPropertyDeviceLocalBit)
+	} else {
+		c.depthImage = c.createAttachment(c.depthFormat, c.sampleCount,
+			vk.ImageUsageDepthStencilAttachmentBit, aspect, vk.MemoryPropertyDeviceLocalBit)
+	}
+	c.nameObject(c.depthImage, "depth/stencil")
+
+	c.vkd.CmdPipelineBarrier(c.cmd,
+		vk.PipelineStageFlags(vk.PipelineStageTopOfPipeBit),
+		vk.PipelineStageFlags(vk.PipelineStageEarlyFragmentTestsBit),
+		0, 0, nil, 0, nil, 1, []vk.ImageMemoryBarrier{{
+			SType:               vk.StructureTypeImageMemoryBarrier,
+			DstAccessMask:       vk.AccessFlags(vk.AccessDepthStencilAttachmentReadBit | vk.AccessDepthStencilAttachmentWriteBit),
+			OldLayout:           vk.ImageLayoutUndefined,
+			NewLayout:           vk.ImageLayoutDepthStencilAttachmentOptimal,
+			SrcQueueFamilyIndex: vk.QueueFamilyIgnored,
+			DstQueueFamilyIndex: vk.QueueFamilyIgnored,
+			Image:               c.depthImage.Image,
+			SubresourceRange: vk.ImageSubresourceRange{
+				AspectMask: aspect,
+				LevelCount: 1,
+				LayerCount: 1,
+			},
+		}})
+}
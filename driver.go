@@ -0,0 +1,57 @@
+package asche
+
+import (
+	"log"
+	"sync"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	"github.com/vulkan-go/asche/trace"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// vkd is the driver new platforms make their Vulkan calls through, tests replace it with a fake driver.
+// Each platform wraps it into its own driver for tracing, leak tracking and allocation stats.
+var vkd driver.Driver = driver.Vulkan{}
+
+// devices maps the devices of the platforms to what the helpers taking a device need,
+// so they make their calls through the driver of its platform.
+var devices sync.Map
+
+type deviceInfo struct {
+	vkd        driver.Driver
+	apiVersion vk.Version
+}
+
+func registerDevice(device vk.Device, d driver.Driver, apiVersion vk.Version) {
+	devices.Store(device, &deviceInfo{
+		vkd:        d,
+		apiVersion: apiVersion,
+	})
+}
+
+func unregisterDevice(device vk.Device) {
+	devices.Delete(device)
+}
+
+// deviceDriver gets the driver of the platform that created the device.
+func deviceDriver(device vk.Device) driver.Driver {
+	if info, ok := devices.Load(device); ok {
+		return info.(*deviceInfo).vkd
+	}
+	return vkd
+}
+
+// deviceAPIVersion gets the Vulkan version negotiated for the device, it's false for devices
+// not created by asche.
+func deviceAPIVersion(device vk.Device) (vk.Version, bool) {
+	if info, ok := devices.Load(device); ok {
+		return info.(*deviceInfo).apiVersion, true
+	}
+	return 0, false
+}
+
+func flushTrace(w *trace.Writer) {
+	if err := w.Flush(); err != nil {
+		log.Println("vulkan warning: failed to write trace:", err)
+	}
+}
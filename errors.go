@@ -1,17 +1,41 @@
 package asche
 
 import (
+	"errors"
 	"fmt"
 	"runtime"
 
 	vk "github.com/vulkan-go/vulkan"
 )
 
+// ErrDeviceLost matches errors caused by vk.ErrorDeviceLost using errors.Is,
+// the device can be re-created with Platform.RecoverDevice.
+var ErrDeviceLost = errors.New("vulkan error: device lost")
+
+type deviceLostError struct {
+	error
+}
+
+func (e deviceLostError) Is(target error) bool {
+	return target == ErrDeviceLost
+}
+
+func (e deviceLostError) Unwrap() error {
+	return e.error
+}
+
 func isError(ret vk.Result) bool {
 	return ret != vk.Success
 }
 
 func NewError(ret vk.Result) error {
+	if ret == vk.ErrorDeviceLost {
+		return deviceLostError{newError(ret)}
+	}
+	return newError(ret)
+}
+
+func newError(ret vk.Result) error {
 	if ret != vk.Success {
 		pc, _, _, ok := runtime.Caller(0)
 		if !ok {
@@ -36,6 +60,11 @@ func orPanic(err error, finalizers ...func()) {
 
 func checkErr(err *error) {
 	if v := recover(); v != nil {
+		if e, ok := v.(error); ok && errors.Is(e, ErrDeviceLost) {
+			// keep the error matchable
+			*err = e
+			return
+		}
 		*err = fmt.Errorf("%+v", v)
 	}
 }
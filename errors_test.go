@@ -0,0 +1,61 @@
+package asche
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestCheckErr(t *testing.T) {
+	tests := []struct {
+		name       string
+		panic      func()
+		deviceLost bool
+		want       string
+	}{
+		{
+			name:  "vulkan error",
+			panic: func() { orPanic(NewError(vk.ErrorOutOfHostMemory)) },
+			want:  "vulkan error: ",
+		},
+		{
+			name:       "device lost",
+			panic:      func() { orPanic(NewError(vk.ErrorDeviceLost)) },
+			deviceLost: true,
+			want:       "vulkan error: ",
+		},
+		{
+			name: "runtime error",
+			panic: func() {
+				var s []int
+				_ = s[len(s)]
+			},
+			want: "runtime error: index out of range",
+		},
+		{
+			name:  "value",
+			panic: func() { panic("boom") },
+			want:  "boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := func() (err error) {
+				defer checkErr(&err)
+				tt.panic()
+				return nil
+			}()
+			if err == nil {
+				t.Fatal("panic not recovered into an error")
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got %q, want prefix %q", err, tt.want)
+			}
+			if got := errors.Is(err, ErrDeviceLost); got != tt.deviceLost {
+				t.Errorf("errors.Is(err, ErrDeviceLost) = %v, want %v", got, tt.deviceLost)
+			}
+		})
+	}
+}
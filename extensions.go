@@ -0,0 +1,191 @@
+package asche
+
+import (
+	"fmt"
+	"strings"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// extensionDependencies lists the extensions required by an extension, the ones promoted to core
+// in the negotiated version are skipped, see promotedExtensions.
+var extensionDependencies = map[string][]string{
+	// instance extensions
+	"VK_KHR_android_surface":                 {"VK_KHR_surface"},
+	"VK_KHR_display":                         {"VK_KHR_surface"},
+	"VK_KHR_get_surface_capabilities2":       {"VK_KHR_surface"},
+	"VK_KHR_wayland_surface":                 {"VK_KHR_surface"},
+	"VK_KHR_win32_surface":                   {"VK_KHR_surface"},
+	"VK_KHR_xcb_surface":                     {"VK_KHR_surface"},
+	"VK_KHR_xlib_surface":                    {"VK_KHR_surface"},
+	"VK_EXT_metal_surface":                   {"VK_KHR_surface"},
+	"VK_MVK_ios_surface":                     {"VK_KHR_surface"},
+	"VK_MVK_macos_surface":                   {"VK_KHR_surface"},
+	"VK_KHR_external_fence_capabilities":     {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_external_memory_capabilities":    {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_external_semaphore_capabilities": {"VK_KHR_get_physical_device_properties2"},
+
+	// device extensions
+	"VK_KHR_swapchain":                      {"VK_KHR_surface"},
+	"VK_KHR_display_swapchain":              {"VK_KHR_swapchain", "VK_KHR_display"},
+	"VK_KHR_incremental_present":            {"VK_KHR_swapchain"},
+	"VK_KHR_16bit_storage":                  {"VK_KHR_get_physical_device_properties2", "VK_KHR_storage_buffer_storage_class"},
+	"VK_KHR_8bit_storage":                   {"VK_KHR_get_physical_device_properties2", "VK_KHR_storage_buffer_storage_class"},
+	"VK_KHR_acceleration_structure":         {"VK_EXT_descriptor_indexing", "VK_KHR_buffer_device_address", "VK_KHR_deferred_host_operations"},
+	"VK_KHR_buffer_device_address":          {"VK_KHR_get_physical_device_properties2", "VK_KHR_device_group"},
+	"VK_KHR_create_renderpass2":             {"VK_KHR_multiview", "VK_KHR_maintenance2"},
+	"VK_KHR_dedicated_allocation":           {"VK_KHR_get_memory_requirements2"},
+	"VK_KHR_depth_stencil_resolve":          {"VK_KHR_create_renderpass2"},
+	"VK_KHR_device_group":                   {"VK_KHR_device_group_creation"},
+	"VK_KHR_driver_properties":              {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_dynamic_rendering":              {"VK_KHR_depth_stencil_resolve", "VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_external_fence":                 {"VK_KHR_external_fence_capabilities"},
+	"VK_KHR_external_fence_fd":              {"VK_KHR_external_fence"},
+	"VK_KHR_external_fence_win32":           {"VK_KHR_external_fence"},
+	"VK_KHR_external_memory":                {"VK_KHR_external_memory_capabilities"},
+	"VK_KHR_external_memory_fd":             {"VK_KHR_external_memory"},
+	"VK_KHR_external_memory_win32":          {"VK_KHR_external_memory"},
+	"VK_KHR_external_semaphore":             {"VK_KHR_external_semaphore_capabilities"},
+	"VK_KHR_external_semaphore_fd":          {"VK_KHR_external_semaphore"},
+	"VK_KHR_external_semaphore_win32":       {"VK_KHR_external_semaphore"},
+	"VK_KHR_imageless_framebuffer":          {"VK_KHR_maintenance2", "VK_KHR_image_format_list", "VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_maintenance3":                   {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_multiview":                      {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_portability_subset":             {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_ray_query":                      {"VK_KHR_spirv_1_4", "VK_KHR_acceleration_structure"},
+	"VK_KHR_ray_tracing_pipeline":           {"VK_KHR_spirv_1_4", "VK_KHR_acceleration_structure"},
+	"VK_KHR_sampler_ycbcr_conversion":       {"VK_KHR_maintenance1", "VK_KHR_bind_memory2", "VK_KHR_get_memory_requirements2", "VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_separate_depth_stencil_layouts": {"VK_KHR_get_physical_device_properties2", "VK_KHR_create_renderpass2"},
+	"VK_KHR_shader_atomic_int64":            {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_shader_float16_int8":            {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_shader_float_controls":          {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_spirv_1_4":                      {"VK_KHR_shader_float_controls"},
+	"VK_KHR_synchronization2":               {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_timeline_semaphore":             {"VK_KHR_get_physical_device_properties2"},
+	"VK_KHR_variable_pointers":              {"VK_KHR_get_physical_device_properties2", "VK_KHR_storage_buffer_storage_class"},
+	"VK_KHR_vulkan_memory_model":            {"VK_KHR_get_physical_device_properties2"},
+	"VK_EXT_descriptor_indexing":            {"VK_KHR_get_physical_device_properties2", "VK_KHR_maintenance3"},
+	"VK_EXT_extended_dynamic_state":         {"VK_KHR_get_physical_device_properties2"},
+	"VK_EXT_memory_budget":                  {"VK_KHR_get_physical_device_properties2"},
+	"VK_EXT_mesh_shader":                    {"VK_KHR_spirv_1_4"},
+	"VK_EXT_scalar_block_layout":            {"VK_KHR_get_physical_device_properties2"},
+}
+
+// instanceLevelExtensions are the extensions enabled on the instance, others are device extensions.
+var instanceLevelExtensions = map[string]bool{
+	"VK_KHR_surface":                         true,
+	"VK_KHR_android_surface":                 true,
+	"VK_KHR_display":                         true,
+	"VK_KHR_get_surface_capabilities2":       true,
+	"VK_KHR_wayland_surface":                 true,
+	"VK_KHR_win32_surface":                   true,
+	"VK_KHR_xcb_surface":                     true,
+	"VK_KHR_xlib_surface":                    true,
+	"VK_EXT_metal_surface":                   true,
+	"VK_MVK_ios_surface":                     true,
+	"VK_MVK_macos_surface":                   true,
+	"VK_KHR_device_group_creation":           true,
+	"VK_KHR_external_fence_capabilities":     true,
+	"VK_KHR_external_memory_capabilities":    true,
+	"VK_KHR_external_semaphore_capabilities": true,
+	"VK_KHR_get_physical_device_properties2": true,
+	"VK_EXT_debug_report":                    true,
+	"VK_EXT_debug_utils":                     true,
+}
+
+// extensionMinVersions lists the extensions that can't be used with Vulkan 1.0.
+var extensionMinVersions = map[string]vk.Version{
+	"VK_KHR_acceleration_structure": makeVersion(1, 1),
+	"VK_KHR_maintenance4":           makeVersion(1, 1),
+	"VK_KHR_spirv_1_4":              makeVersion(1, 1),
+}
+
+// dependency is a prerequisite of an extension.
+type dependency struct {
+	name       string
+	requiredBy string
+}
+
+// dependencies gets the prerequisites of the extensions recursively, except the ones
+// already listed and the ones promoted to core, checked against the version of their level.
+func dependencies(extensions []string, deviceVersion, instanceVersion vk.Version) []dependency {
+	seen := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		seen[strings.TrimSuffix(ext, end)] = true
+	}
+	var deps []dependency
+	var visit func(ext string)
+	visit = func(ext string) {
+		for _, dep := range extensionDependencies[ext] {
+			if seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			version := deviceVersion
+			if instanceLevelExtensions[dep] {
+				version = instanceVersion
+			}
+			if IsPromoted(dep, version) {
+				continue
+			}
+			deps = append(deps, dependency{
+				name:       dep,
+				requiredBy: ext,
+			})
+			visit(dep)
+		}
+	}
+	for _, ext := range extensions {
+		visit(strings.TrimSuffix(ext, end))
+	}
+	return deps
+}
+
+// instanceDependencies gets the instance extensions required by the instance and device extensions,
+// device extensions promoted to core are not known before a device is chosen, so all are followed.
+func instanceDependencies(extensions []string, instanceVersion vk.Version) []string {
+	var names []string
+	for _, dep := range dependencies(extensions, makeVersion(1, 0), instanceVersion) {
+		if instanceLevelExtensions[dep.name] {
+			names = append(names, safeString(dep.name))
+		}
+	}
+	return names
+}
+
+// resolveDependencies adds the prerequisites of the enabled extensions of one level, prerequisites
+// of the other level must be enabled already. The error names the first prerequisite that is not available.
+func resolveDependencies(enabled, available, enabledOther []string, instanceLevel bool,
+	deviceVersion, instanceVersion vk.Version) ([]string, error) {
+
+	version := deviceVersion
+	if instanceLevel {
+		version = instanceVersion
+	}
+	for _, ext := range enabled {
+		name := strings.TrimSuffix(ext, end)
+		if min, ok := extensionMinVersions[name]; ok && version < min {
+			return nil, fmt.Errorf("vulkan error: %s requires Vulkan %d.%d", name, min.Major(), min.Minor())
+		}
+	}
+	for _, dep := range dependencies(enabled, deviceVersion, instanceVersion) {
+		ext := safeString(dep.name)
+		if instanceLevelExtensions[dep.name] != instanceLevel {
+			if !hasString(enabledOther, ext) {
+				return nil, fmt.Errorf("vulkan error: %s requires %s, which is not available",
+					dep.requiredBy, dep.name)
+			}
+			continue
+		}
+		if existing, _ := checkExisting(available, []string{ext}); len(existing) == 0 {
+			return nil, fmt.Errorf("vulkan error: %s requires %s, which is not available",
+				dep.requiredBy, dep.name)
+		}
+		if min, ok := extensionMinVersions[dep.name]; ok && version < min {
+			return nil, fmt.Errorf("vulkan error: %s requires %s, which requires Vulkan %d.%d",
+				dep.requiredBy, dep.name, min.Major(), min.Minor())
+		}
+		enabled = mergeExtensions(enabled, ext)
+	}
+	return enabled, nil
+}
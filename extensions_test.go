@@ -0,0 +1,103 @@
+package asche
+
+import (
+	"reflect"
+	"testing"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestResolveDependencies(t *testing.T) {
+	tests := []struct {
+		name          string
+		enabled       []string
+		available     []string
+		enabledOther  []string
+		instanceLevel bool
+		version       vk.Version
+		want          []string
+		wantErr       string
+	}{
+		{
+			name:         "other level enabled",
+			enabled:      []string{"VK_KHR_swapchain\x00"},
+			enabledOther: []string{"VK_KHR_surface\x00"},
+			version:      makeVersion(1, 0),
+			want:         []string{"VK_KHR_swapchain\x00"},
+		},
+		{
+			name:    "other level missing",
+			enabled: []string{"VK_KHR_swapchain\x00"},
+			version: makeVersion(1, 0),
+			wantErr: "vulkan error: VK_KHR_swapchain requires VK_KHR_surface, which is not available",
+		},
+		{
+			name:         "added",
+			enabled:      []string{"VK_KHR_16bit_storage\x00"},
+			available:    []string{"VK_KHR_16bit_storage\x00", "VK_KHR_storage_buffer_storage_class\x00"},
+			enabledOther: []string{"VK_KHR_get_physical_device_properties2\x00"},
+			version:      makeVersion(1, 0),
+			want:         []string{"VK_KHR_16bit_storage\x00", "VK_KHR_storage_buffer_storage_class\x00"},
+		},
+		{
+			name:    "promoted",
+			enabled: []string{"VK_KHR_16bit_storage\x00"},
+			version: makeVersion(1, 1),
+			want:    []string{"VK_KHR_16bit_storage\x00"},
+		},
+		{
+			name:         "not available",
+			enabled:      []string{"VK_KHR_16bit_storage\x00"},
+			available:    []string{"VK_KHR_16bit_storage\x00"},
+			enabledOther: []string{"VK_KHR_get_physical_device_properties2\x00"},
+			version:      makeVersion(1, 0),
+			wantErr:      "vulkan error: VK_KHR_16bit_storage requires VK_KHR_storage_buffer_storage_class, which is not available",
+		},
+		{
+			name:      "recursive",
+			enabled:   []string{"VK_EXT_mesh_shader\x00"},
+			available: []string{"VK_KHR_spirv_1_4\x00", "VK_KHR_shader_float_controls\x00"},
+			version:   makeVersion(1, 1),
+			want:      []string{"VK_EXT_mesh_shader\x00", "VK_KHR_spirv_1_4\x00", "VK_KHR_shader_float_controls\x00"},
+		},
+		{
+			name:    "enabled requires newer version",
+			enabled: []string{"VK_KHR_spirv_1_4\x00"},
+			version: makeVersion(1, 0),
+			wantErr: "vulkan error: VK_KHR_spirv_1_4 requires Vulkan 1.1",
+		},
+		{
+			name:      "prerequisite requires newer version",
+			enabled:   []string{"VK_EXT_mesh_shader\x00"},
+			available: []string{"VK_KHR_spirv_1_4\x00"},
+			version:   makeVersion(1, 0),
+			wantErr:   "vulkan error: VK_EXT_mesh_shader requires VK_KHR_spirv_1_4, which requires Vulkan 1.1",
+		},
+		{
+			name:          "instance level",
+			enabled:       []string{"VK_KHR_xcb_surface\x00"},
+			available:     []string{"VK_KHR_surface\x00", "VK_KHR_xcb_surface\x00"},
+			instanceLevel: true,
+			version:       makeVersion(1, 0),
+			want:          []string{"VK_KHR_xcb_surface\x00", "VK_KHR_surface\x00"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveDependencies(tt.enabled, tt.available, tt.enabledOther, tt.instanceLevel,
+				tt.version, tt.version)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
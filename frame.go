@@ -0,0 +1,111 @@
+package asche
+
+import (
+	"errors"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Frame is a frame in flight started with BeginFrame and finished with EndFrame.
+type Frame struct {
+	// ImageIndex is the index of the acquired swapchain image.
+	ImageIndex int
+	// CommandBuffer is the primary command buffer of the frame slot, it has been reset
+	// and begun by BeginFrame, the application records the frame into it.
+	CommandBuffer vk.CommandBuffer
+	// Resources are the resources of the acquired swapchain image.
+	Resources *SwapchainImageResources
+
+	slot int
+}
+
+// prepareFrames creates a resettable command buffer and a fence per frame slot,
+// fences are created signaled so the first wait on each slot returns immediately.
+func (c *context) prepareFrames() {
+	var cmdPool vk.CommandPool
+	ret := c.vkd.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
+		SType:            vk.StructureTypeCommandPoolCreateInfo,
+		Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateResetCommandBufferBit),
+		QueueFamilyIndex: c.platform.GraphicsQueueFamilyIndex(),
+	}, nil, &cmdPool)
+	orPanic(NewError(ret))
+	c.frameCmdPool = cmdPool
+	c.nameObject(c.frameCmdPool, "frame command pool")
+
+	c.frameCmds = make([]vk.CommandBuffer, c.frameLag)
+	ret = c.vkd.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
+		SType:              vk.StructureTypeCommandBufferAllocateInfo,
+		CommandPool:        c.frameCmdPool,
+		Level:              vk.CommandBufferLevelPrimary,
+		CommandBufferCount: uint32(c.frameLag),
+	}, c.frameCmds)
+	orPanic(NewError(ret))
+
+	c.frameSerials = make([]uint64, c.frameLag)
+	c.frameFences = make([]vk.Fence, c.frameLag)
+	for i := 0; i < c.frameLag; i++ {
+		ret = c.vkd.CreateFence(c.device, &vk.FenceCreateInfo{
+			SType: vk.StructureTypeFenceCreateInfo,
+			Flags: vk.FenceCreateFlags(vk.FenceCreateSignaledBit),
+		}, nil, &c.frameFences[i])
+		orPanic(NewError(ret))
+		c.nameObject(c.frameFences[i], "frame fence %d", i)
+		c.nameObject(c.frameCmds[i], "frame command buffer %d", i)
+	}
+}
+
+func (c *context) destroyFrames() {
+	for _, fence := range c.frameFences {
+		c.vkd.DestroyFence(c.device, fence, nil)
+	}
+	c.frameFences = nil
+	if c.frameCmdPool != vk.NullCommandPool {
+		c.vkd.DestroyCommandPool(c.device, c.frameCmdPool, nil)
+		c.frameCmdPool = vk.NullCommandPool
+	}
+	c.frameCmds = nil
+}
+
+func (c *context) BeginFrame() (frame *Frame, outdated bool, err error) {
+	defer c.checkValidation(&err)
+	defer checkErr(&err)
+	if c.frame != nil {
+		orPanic(errors.New("vulkan error: BeginFrame called twice without EndFrame"))
+	}
+
+	imageIndex, outdated := c.acquire()
+	if outdated {
+		return nil, true, nil
+	}
+	c.workerPools.reset(c.device, c.frameIndex)
+	cmd := c.frameCmds[c.frameIndex]
+	ret := c.vkd.ResetCommandBuffer(cmd, 0)
+	orPanic(NewError(ret))
+	ret = c.vkd.BeginCommandBuffer(cmd, &vk.CommandBufferBeginInfo{
+		SType: vk.StructureTypeCommandBufferBeginInfo,
+		Flags: vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit),
+	})
+	orPanic(NewError(ret))
+
+	c.frame = &Frame{
+		ImageIndex:    imageIndex,
+		CommandBuffer: cmd,
+		Resources:     c.swapchainImageResources[imageIndex],
+		slot:          c.frameIndex,
+	}
+	return c.frame, false, nil
+}
+
+func (c *context) EndFrame(frame *Frame) (outdated bool, err error) {
+	defer c.checkValidation(&err)
+	defer checkErr(&err)
+	if frame == nil || frame != c.frame {
+		orPanic(errors.New("vulkan error: EndFrame called with a frame that is not in flight"))
+	}
+	c.frame = nil
+
+	ret := c.vkd.EndCommandBuffer(frame.CommandBuffer)
+	orPanic(NewError(ret))
+	c.submit(frame.ImageIndex, frame.CommandBuffer)
+	return c.PresentImage(frame.ImageIndex)
+}
@@ -0,0 +1,47 @@
+package asche
+
+import (
+	"testing"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestFrameSubmitFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		result vk.Result
+	}{
+		{name: "out of host memory", result: vk.ErrorOutOfHostMemory},
+		{name: "out of device memory", result: vk.ErrorOutOfDeviceMemory},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			newFakePlatform(t, app)
+			ctx := app.Context()
+
+			failed := false
+			app.fd.Fail = func(call string) vk.Result {
+				if call == "QueueSubmit" && !failed {
+					failed = true
+					return tt.result
+				}
+				return vk.Success
+			}
+			frame, _, err := ctx.BeginFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := ctx.EndFrame(frame); err == nil {
+				t.Fatal("EndFrame succeeded")
+			}
+			// the slot of the failed frame is reused after the others
+			for i := 0; i < 4; i++ {
+				if drawFrame(t, ctx) {
+					t.Fatal("frame outdated")
+				}
+			}
+		})
+	}
+}
@@ -129,3 +129,13 @@ func packageAndName(fn *runtime.Func) (string, string) {
 	name = strings.Replace(name, "·", ".", -1)
 	return pkg, name
 }
+
+func clampUint32(v, lo, hi uint32) uint32 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
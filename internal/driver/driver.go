@@ -0,0 +1,86 @@
+// Package driver routes the Vulkan calls made by asche through an interface,
+// so they can be served by a fake implementation on machines without a GPU.
+package driver
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Driver is the subset of the Vulkan API used by asche.
+type Driver interface {
+	AcquireNextImage(device vk.Device, swapchain vk.Swapchain, timeout uint64, semaphore vk.Semaphore, fence vk.Fence, pImageIndex *uint32) vk.Result
+	AllocateCommandBuffers(device vk.Device, pAllocateInfo *vk.CommandBufferAllocateInfo, pCommandBuffers []vk.CommandBuffer) vk.Result
+	AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo, pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result
+	BeginCommandBuffer(commandBuffer vk.CommandBuffer, pBeginInfo *vk.CommandBufferBeginInfo) vk.Result
+	BindBufferMemory(device vk.Device, buffer vk.Buffer, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result
+	BindImageMemory(device vk.Device, image vk.Image, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result
+	CmdBeginDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel)
+	CmdBeginRenderPass(commandBuffer vk.CommandBuffer, pRenderPassBegin *vk.RenderPassBeginInfo, contents vk.SubpassContents)
+	CmdEndDebugUtilsLabel(commandBuffer vk.CommandBuffer)
+	CmdExecuteCommands(commandBuffer vk.CommandBuffer, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer)
+	CmdInsertDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel)
+	CmdPipelineBarrier(commandBuffer vk.CommandBuffer, srcStageMask vk.PipelineStageFlags, dstStageMask vk.PipelineStageFlags, dependencyFlags vk.DependencyFlags, memoryBarrierCount uint32, pMemoryBarriers []vk.MemoryBarrier, bufferMemoryBarrierCount uint32, pBufferMemoryBarriers []vk.BufferMemoryBarrier, imageMemoryBarrierCount uint32, pImageMemoryBarriers []vk.ImageMemoryBarrier)
+	CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo, pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result
+	CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo, pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result
+	CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo, pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result
+	CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo, pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result
+	CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo, pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result
+	CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo, pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result
+	CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo, pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result
+	CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo, pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result
+	CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks, pInstance *vk.Instance) vk.Result
+	CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo, pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result
+	CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo, pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result
+	CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo, pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result
+	CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo, pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result
+	DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks)
+	DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks)
+	DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback, pAllocator *vk.AllocationCallbacks)
+	DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks)
+	DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks)
+	DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks)
+	DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks)
+	DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks)
+	DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks)
+	DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks)
+	DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks)
+	DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks)
+	DestroySurface(instance vk.Instance, surface vk.Surface, pAllocator *vk.AllocationCallbacks)
+	DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks)
+	DeviceWaitIdle(device vk.Device) vk.Result
+	EndCommandBuffer(commandBuffer vk.CommandBuffer) vk.Result
+	EnumerateDeviceExtensionProperties(physicalDevice vk.PhysicalDevice, pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result
+	EnumerateInstanceExtensionProperties(pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result
+	EnumerateInstanceVersion(pApiVersion *uint32) vk.Result
+	EnumerateInstanceLayerProperties(pPropertyCount *uint32, pProperties []vk.LayerProperties) vk.Result
+	EnumeratePhysicalDevices(instance vk.Instance, pPhysicalDeviceCount *uint32, pPhysicalDevices []vk.PhysicalDevice) vk.Result
+	FreeCommandBuffers(device vk.Device, commandPool vk.CommandPool, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer)
+	FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks)
+	GetBufferMemoryRequirements(device vk.Device, buffer vk.Buffer, pMemoryRequirements *vk.MemoryRequirements)
+	GetDeviceQueue(device vk.Device, queueFamilyIndex uint32, queueIndex uint32, pQueue *vk.Queue)
+	GetFenceStatus(device vk.Device, fence vk.Fence) vk.Result
+	GetImageMemoryRequirements(device vk.Device, image vk.Image, pMemoryRequirements *vk.MemoryRequirements)
+	GetPhysicalDeviceFormatProperties(physicalDevice vk.PhysicalDevice, format vk.Format, pFormatProperties *vk.FormatProperties)
+	GetPhysicalDeviceMemoryProperties(physicalDevice vk.PhysicalDevice, pMemoryProperties *vk.PhysicalDeviceMemoryProperties)
+	GetPhysicalDeviceProperties(physicalDevice vk.PhysicalDevice, pProperties *vk.PhysicalDeviceProperties)
+	GetPhysicalDeviceQueueFamilyProperties(physicalDevice vk.PhysicalDevice, pQueueFamilyPropertyCount *uint32, pQueueFamilyProperties []vk.QueueFamilyProperties)
+	GetPhysicalDeviceSurfaceCapabilities(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceCapabilities *vk.SurfaceCapabilities) vk.Result
+	GetPhysicalDeviceSurfaceFormats(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceFormatCount *uint32, pSurfaceFormats []vk.SurfaceFormat) vk.Result
+	GetPhysicalDeviceSurfaceSupport(physicalDevice vk.PhysicalDevice, queueFamilyIndex uint32, surface vk.Surface, pSupported *vk.Bool32) vk.Result
+	GetSwapchainImages(device vk.Device, swapchain vk.Swapchain, pSwapchainImageCount *uint32, pSwapchainImages []vk.Image) vk.Result
+	InitInstance(instance vk.Instance) error
+	MapMemory(device vk.Device, memory vk.DeviceMemory, offset vk.DeviceSize, size vk.DeviceSize, flags vk.MemoryMapFlags, ppData *unsafe.Pointer) vk.Result
+	QueueBeginDebugUtilsLabel(queue vk.Queue, pLabelInfo *vk.DebugUtilsLabel)
+	QueueEndDebugUtilsLabel(queue vk.Queue)
+	QueuePresent(queue vk.Queue, pPresentInfo *vk.PresentInfo) vk.Result
+	QueueSubmit(queue vk.Queue, submitCount uint32, pSubmits []vk.SubmitInfo, fence vk.Fence) vk.Result
+	QueueWaitIdle(queue vk.Queue) vk.Result
+	ResetCommandBuffer(commandBuffer vk.CommandBuffer, flags vk.CommandBufferResetFlags) vk.Result
+	ResetCommandPool(device vk.Device, commandPool vk.CommandPool, flags vk.CommandPoolResetFlags) vk.Result
+	ResetFences(device vk.Device, fenceCount uint32, pFences []vk.Fence) vk.Result
+	SetDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result
+	UnmapMemory(device vk.Device, memory vk.DeviceMemory)
+	WaitForFences(device vk.Device, fenceCount uint32, pFences []vk.Fence, waitAll vk.Bool32, timeout uint64) vk.Result
+}
@@ -0,0 +1,58 @@
+package fake
+
+/*
+#include <stddef.h>
+
+// asche_fake_allocation_callbacks mirrors VkAllocationCallbacks.
+typedef struct {
+	void *pUserData;
+	void *(*pfnAllocation)(void *, size_t, size_t, int);
+	void *(*pfnReallocation)(void *, void *, size_t, size_t, int);
+	void (*pfnFree)(void *, void *);
+	void (*pfnInternalAllocation)(void *, size_t, int, int);
+	void (*pfnInternalFree)(void *, size_t, int, int);
+} asche_fake_allocation_callbacks;
+
+static void *asche_fake_allocation(asche_fake_allocation_callbacks *cb, size_t size, size_t alignment, int scope) {
+	return cb->pfnAllocation(cb->pUserData, size, alignment, scope);
+}
+
+static void *asche_fake_reallocation(asche_fake_allocation_callbacks *cb, void *orig, size_t size,
+	size_t alignment, int scope) {
+
+	return cb->pfnReallocation(cb->pUserData, orig, size, alignment, scope);
+}
+
+static void asche_fake_free(asche_fake_allocation_callbacks *cb, void *mem) {
+	cb->pfnFree(cb->pUserData, mem);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func allocationCallbacks(pAllocator *vk.AllocationCallbacks) *C.asche_fake_allocation_callbacks {
+	return (*C.asche_fake_allocation_callbacks)(unsafe.Pointer(pAllocator))
+}
+
+// Allocate calls pfnAllocation of the callbacks the way a driver does, it's nil if out of memory.
+func Allocate(pAllocator *vk.AllocationCallbacks, size, alignment uint, scope vk.SystemAllocationScope) unsafe.Pointer {
+	return C.asche_fake_allocation(allocationCallbacks(pAllocator), C.size_t(size), C.size_t(alignment), C.int(scope))
+}
+
+// Reallocate calls pfnReallocation of the callbacks the way a driver does.
+func Reallocate(pAllocator *vk.AllocationCallbacks, orig unsafe.Pointer, size, alignment uint,
+	scope vk.SystemAllocationScope) unsafe.Pointer {
+
+	return C.asche_fake_reallocation(allocationCallbacks(pAllocator), orig, C.size_t(size),
+		C.size_t(alignment), C.int(scope))
+}
+
+// Free calls pfnFree of the callbacks the way a driver does.
+func Free(pAllocator *vk.AllocationCallbacks, mem unsafe.Pointer) {
+	C.asche_fake_free(allocationCallbacks(pAllocator), mem)
+}
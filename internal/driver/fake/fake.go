@@ -0,0 +1,1099 @@
+// Package fake implements an in-memory driver.Driver, so asche can be tested without a GPU.
+// Submitted work completes immediately, objects are opaque handles tracked for leak checks.
+package fake
+
+// #include <stdlib.h>
+import "C"
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+var _ driver.Driver = (*Driver)(nil)
+
+// QueueFamily describes a queue family of a fake physical device.
+type QueueFamily struct {
+	Properties vk.QueueFamilyProperties
+	// Present reports whether the queue family can present to the fake surface.
+	Present bool
+}
+
+// PhysicalDevice describes a fake physical device.
+type PhysicalDevice struct {
+	Properties       vk.PhysicalDeviceProperties
+	MemoryProperties vk.PhysicalDeviceMemoryProperties
+	QueueFamilies    []QueueFamily
+	Extensions       []string
+	// Formats are the properties of supported formats, others have no features.
+	Formats map[vk.Format]vk.FormatProperties
+}
+
+// Surface describes the fake surface.
+type Surface struct {
+	Capabilities vk.SurfaceCapabilities
+	Formats      []vk.SurfaceFormat
+}
+
+// Driver is a fake driver.Driver, the exported fields configure it and may be
+// changed between calls, e.g. to resize the surface.
+type Driver struct {
+	// InstanceVersion is the version reported by EnumerateInstanceVersion, 1.0 if not set.
+	InstanceVersion    uint32
+	InstanceExtensions []string
+	Layers             []string
+	// LayerExtensions are the instance extensions provided by layers listed in Layers.
+	LayerExtensions map[string][]string
+	Devices         []PhysicalDevice
+	Surface         Surface
+
+	// Fail injects failures, it's called with the function name before every call
+	// and a result other than vk.Success is returned by the call instead of performing it.
+	Fail func(call string) vk.Result
+	// AcquireResults are returned by AcquireNextImage in order, then vk.Success.
+	AcquireResults []vk.Result
+	// PresentResults are returned by QueuePresent in order, then vk.Success.
+	PresentResults []vk.Result
+
+	mu         sync.Mutex
+	calls      []string
+	objects    map[unsafe.Pointer]string
+	gpus       map[vk.PhysicalDevice]int
+	devices    map[vk.Device]int
+	fences     map[vk.Fence]bool
+	swapchains map[vk.Swapchain]*swapchain
+	sizes      map[unsafe.Pointer]vk.DeviceSize
+	memory     map[vk.DeviceMemory][]byte
+	names      map[uint64]string
+}
+
+type swapchain struct {
+	images []vk.Image
+	next   int
+}
+
+// New creates a fake Vulkan 1.1 driver with a single device that has a graphics queue family
+// able to present, host visible and device local memory, and a 640x480 surface.
+func New() *Driver {
+	var memProps vk.PhysicalDeviceMemoryProperties
+	memProps.MemoryTypeCount = 2
+	memProps.MemoryTypes[0].PropertyFlags = vk.MemoryPropertyFlags(vk.MemoryPropertyDeviceLocalBit)
+	memProps.MemoryTypes[1].PropertyFlags = vk.MemoryPropertyFlags(vk.MemoryPropertyHostVisibleBit |
+		vk.MemoryPropertyHostCoherentBit)
+	memProps.MemoryHeapCount = 1
+
+	var props vk.PhysicalDeviceProperties
+	props.ApiVersion = vk.MakeVersion(1, 1, 0)
+	props.DeviceType = vk.PhysicalDeviceTypeCpu
+	copy(props.DeviceName[:], "asche fake device\x00")
+	props.Limits.MaxImageDimension2D = 4096
+	props.Limits.FramebufferColorSampleCounts = vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount4Bit)
+	props.Limits.FramebufferDepthSampleCounts = vk.SampleCountFlags(vk.SampleCount1Bit | vk.SampleCount4Bit)
+
+	depth := vk.FormatProperties{
+		OptimalTilingFeatures: vk.FormatFeatureFlags(vk.FormatFeatureDepthStencilAttachmentBit),
+	}
+	return &Driver{
+		InstanceVersion:    vk.MakeVersion(1, 1, 0),
+		InstanceExtensions: []string{"VK_KHR_surface"},
+		Devices: []PhysicalDevice{{
+			Properties:       props,
+			MemoryProperties: memProps,
+			Extensions:       []string{"VK_KHR_swapchain"},
+			QueueFamilies: []QueueFamily{{
+				Properties: vk.QueueFamilyProperties{
+					QueueFlags: vk.QueueFlags(vk.QueueGraphicsBit | vk.QueueComputeBit | vk.QueueTransferBit),
+					QueueCount: 1,
+				},
+				Present: true,
+			}},
+			Formats: map[vk.Format]vk.FormatProperties{
+				vk.FormatD32Sfloat:       depth,
+				vk.FormatD24UnormS8Uint:  depth,
+				vk.FormatD32SfloatS8Uint: depth,
+			},
+		}},
+		Surface: Surface{
+			Capabilities: vk.SurfaceCapabilities{
+				MinImageCount:           2,
+				MaxImageCount:           8,
+				CurrentExtent:           vk.Extent2D{Width: 640, Height: 480},
+				MinImageExtent:          vk.Extent2D{Width: 1, Height: 1},
+				MaxImageExtent:          vk.Extent2D{Width: 4096, Height: 4096},
+				MaxImageArrayLayers:     1,
+				SupportedTransforms:     vk.SurfaceTransformFlags(vk.SurfaceTransformIdentityBit),
+				CurrentTransform:        vk.SurfaceTransformIdentityBit,
+				SupportedCompositeAlpha: vk.CompositeAlphaFlags(vk.CompositeAlphaOpaqueBit),
+				SupportedUsageFlags:     vk.ImageUsageFlags(vk.ImageUsageColorAttachmentBit),
+			},
+			Formats: []vk.SurfaceFormat{{
+				Format:     vk.FormatB8g8r8a8Unorm,
+				ColorSpace: vk.ColorSpaceSrgbNonlinear,
+			}},
+		},
+	}
+}
+
+// CreateSurface creates a surface handle, use it to implement Application.VulkanSurface.
+func (d *Driver) CreateSurface() vk.Surface {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return vk.Surface(d.create("Surface"))
+}
+
+// Calls gets the names of all the calls made so far, in order.
+func (d *Driver) Calls() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	calls := make([]string, len(d.calls))
+	copy(calls, d.calls)
+	return calls
+}
+
+// Live gets the number of objects alive by object type, e.g. "Fence".
+func (d *Driver) Live() map[string]int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	live := make(map[string]int)
+	for _, kind := range d.objects {
+		live[kind]++
+	}
+	return live
+}
+
+// LiveKinds gets the sorted object types that have objects alive.
+func (d *Driver) LiveKinds() []string {
+	var kinds []string
+	for kind := range d.Live() {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// call records a call and returns the injected result, d.mu must be held.
+func (d *Driver) call(name string) vk.Result {
+	d.calls = append(d.calls, name)
+	if d.Fail != nil {
+		return d.Fail(name)
+	}
+	return vk.Success
+}
+
+// create allocates an opaque handle, d.mu must be held.
+func (d *Driver) create(kind string) unsafe.Pointer {
+	if d.objects == nil {
+		d.objects = make(map[unsafe.Pointer]string)
+	}
+	h := newHandle()
+	d.objects[h] = kind
+	return h
+}
+
+// newHandle allocates an opaque handle outside of the Go heap, vulkan-go declares handles
+// as pointers to incomplete C types, which must not point into the Go heap. Handles are never freed,
+// so a stale one can't alias a new object.
+func newHandle() unsafe.Pointer {
+	return C.malloc(8)
+}
+
+// destroy releases an opaque handle, d.mu must be held.
+func (d *Driver) destroy(h unsafe.Pointer) {
+	delete(d.objects, h)
+	delete(d.sizes, h)
+}
+
+func (d *Driver) setSize(h unsafe.Pointer, size vk.DeviceSize) {
+	if d.sizes == nil {
+		d.sizes = make(map[unsafe.Pointer]vk.DeviceSize)
+	}
+	d.sizes[h] = size
+}
+
+// gpu gets the physical device description of the handle, d.mu must be held.
+func (d *Driver) gpu(gpu vk.PhysicalDevice) *PhysicalDevice {
+	return &d.Devices[d.gpus[gpu]]
+}
+
+// deviceGPU gets the physical device description of the logical device, d.mu must be held.
+func (d *Driver) deviceGPU(device vk.Device) *PhysicalDevice {
+	return &d.Devices[d.devices[device]]
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func enumerate(count *uint32, n int, fill func(i int)) vk.Result {
+	if fill == nil {
+		*count = uint32(n)
+		return vk.Success
+	}
+	if int(*count) < n {
+		n = int(*count)
+	}
+	for i := 0; i < n; i++ {
+		fill(i)
+	}
+	*count = uint32(n)
+	return vk.Success
+}
+
+func (d *Driver) InitInstance(instance vk.Instance) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("InitInstance")
+	return nil
+}
+
+func (d *Driver) EnumerateInstanceExtensionProperties(pLayerName string, pPropertyCount *uint32,
+	pProperties []vk.ExtensionProperties) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("EnumerateInstanceExtensionProperties"); ret != vk.Success {
+		return ret
+	}
+	extensions := d.InstanceExtensions
+	if layer := strings.TrimSuffix(pLayerName, "\x00"); layer != "" {
+		var ok bool
+		if extensions, ok = d.LayerExtensions[layer]; !ok && !hasString(d.Layers, layer) {
+			return vk.ErrorLayerNotPresent
+		}
+	}
+	var fill func(i int)
+	if pProperties != nil {
+		fill = func(i int) {
+			copy(pProperties[i].ExtensionName[:], extensions[i]+"\x00")
+		}
+	}
+	return enumerate(pPropertyCount, len(extensions), fill)
+}
+
+func (d *Driver) EnumerateInstanceVersion(pApiVersion *uint32) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("EnumerateInstanceVersion"); ret != vk.Success {
+		return ret
+	}
+	*pApiVersion = d.InstanceVersion
+	if *pApiVersion == 0 {
+		*pApiVersion = vk.MakeVersion(1, 0, 0)
+	}
+	return vk.Success
+}
+
+func (d *Driver) EnumerateInstanceLayerProperties(pPropertyCount *uint32, pProperties []vk.LayerProperties) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("EnumerateInstanceLayerProperties"); ret != vk.Success {
+		return ret
+	}
+	var fill func(i int)
+	if pProperties != nil {
+		fill = func(i int) {
+			copy(pProperties[i].LayerName[:], d.Layers[i]+"\x00")
+		}
+	}
+	return enumerate(pPropertyCount, len(d.Layers), fill)
+}
+
+func (d *Driver) EnumerateDeviceExtensionProperties(physicalDevice vk.PhysicalDevice, pLayerName string,
+	pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("EnumerateDeviceExtensionProperties"); ret != vk.Success {
+		return ret
+	}
+	extensions := d.gpu(physicalDevice).Extensions
+	var fill func(i int)
+	if pProperties != nil {
+		fill = func(i int) {
+			copy(pProperties[i].ExtensionName[:], extensions[i]+"\x00")
+		}
+	}
+	return enumerate(pPropertyCount, len(extensions), fill)
+}
+
+func (d *Driver) CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks,
+	pInstance *vk.Instance) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateInstance"); ret != vk.Success {
+		return ret
+	}
+	*pInstance = vk.Instance(d.create("Instance"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyInstance")
+	d.destroy(unsafe.Pointer(instance))
+}
+
+func (d *Driver) CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateDebugReportCallback"); ret != vk.Success {
+		return ret
+	}
+	*pCallback = vk.DebugReportCallback(d.create("DebugReportCallback"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback,
+	pAllocator *vk.AllocationCallbacks) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyDebugReportCallback")
+	d.destroy(unsafe.Pointer(callback))
+}
+
+func (d *Driver) EnumeratePhysicalDevices(instance vk.Instance, pPhysicalDeviceCount *uint32,
+	pPhysicalDevices []vk.PhysicalDevice) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("EnumeratePhysicalDevices"); ret != vk.Success {
+		return ret
+	}
+	if d.gpus == nil {
+		d.gpus = make(map[vk.PhysicalDevice]int)
+	}
+	var fill func(i int)
+	if pPhysicalDevices != nil {
+		fill = func(i int) {
+			for gpu, idx := range d.gpus {
+				if idx == i {
+					pPhysicalDevices[i] = gpu
+					return
+				}
+			}
+			// physical devices are not destroyed, so they are not tracked as objects
+			gpu := vk.PhysicalDevice(newHandle())
+			d.gpus[gpu] = i
+			pPhysicalDevices[i] = gpu
+		}
+	}
+	return enumerate(pPhysicalDeviceCount, len(d.Devices), fill)
+}
+
+func (d *Driver) GetPhysicalDeviceProperties(physicalDevice vk.PhysicalDevice, pProperties *vk.PhysicalDeviceProperties) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetPhysicalDeviceProperties")
+	*pProperties = d.gpu(physicalDevice).Properties
+}
+
+func (d *Driver) GetPhysicalDeviceMemoryProperties(physicalDevice vk.PhysicalDevice,
+	pMemoryProperties *vk.PhysicalDeviceMemoryProperties) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetPhysicalDeviceMemoryProperties")
+	*pMemoryProperties = d.gpu(physicalDevice).MemoryProperties
+}
+
+func (d *Driver) GetPhysicalDeviceFormatProperties(physicalDevice vk.PhysicalDevice, format vk.Format,
+	pFormatProperties *vk.FormatProperties) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetPhysicalDeviceFormatProperties")
+	*pFormatProperties = d.gpu(physicalDevice).Formats[format]
+}
+
+func (d *Driver) GetPhysicalDeviceQueueFamilyProperties(physicalDevice vk.PhysicalDevice,
+	pQueueFamilyPropertyCount *uint32, pQueueFamilyProperties []vk.QueueFamilyProperties) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetPhysicalDeviceQueueFamilyProperties")
+	families := d.gpu(physicalDevice).QueueFamilies
+	var fill func(i int)
+	if pQueueFamilyProperties != nil {
+		fill = func(i int) {
+			pQueueFamilyProperties[i] = families[i].Properties
+		}
+	}
+	enumerate(pQueueFamilyPropertyCount, len(families), fill)
+}
+
+func (d *Driver) GetPhysicalDeviceSurfaceSupport(physicalDevice vk.PhysicalDevice, queueFamilyIndex uint32,
+	surface vk.Surface, pSupported *vk.Bool32) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("GetPhysicalDeviceSurfaceSupport"); ret != vk.Success {
+		return ret
+	}
+	*pSupported = vk.False
+	families := d.gpu(physicalDevice).QueueFamilies
+	if int(queueFamilyIndex) < len(families) && families[queueFamilyIndex].Present {
+		*pSupported = vk.True
+	}
+	return vk.Success
+}
+
+func (d *Driver) GetPhysicalDeviceSurfaceCapabilities(physicalDevice vk.PhysicalDevice, surface vk.Surface,
+	pSurfaceCapabilities *vk.SurfaceCapabilities) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("GetPhysicalDeviceSurfaceCapabilities"); ret != vk.Success {
+		return ret
+	}
+	*pSurfaceCapabilities = d.Surface.Capabilities
+	return vk.Success
+}
+
+func (d *Driver) GetPhysicalDeviceSurfaceFormats(physicalDevice vk.PhysicalDevice, surface vk.Surface,
+	pSurfaceFormatCount *uint32, pSurfaceFormats []vk.SurfaceFormat) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("GetPhysicalDeviceSurfaceFormats"); ret != vk.Success {
+		return ret
+	}
+	var fill func(i int)
+	if pSurfaceFormats != nil {
+		fill = func(i int) {
+			pSurfaceFormats[i] = d.Surface.Formats[i]
+		}
+	}
+	return enumerate(pSurfaceFormatCount, len(d.Surface.Formats), fill)
+}
+
+func (d *Driver) DestroySurface(instance vk.Instance, surface vk.Surface, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroySurface")
+	d.destroy(unsafe.Pointer(surface))
+}
+
+func (d *Driver) CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateDevice"); ret != vk.Success {
+		return ret
+	}
+	families := d.gpu(physicalDevice).QueueFamilies
+	for _, info := range pCreateInfo.PQueueCreateInfos {
+		if int(info.QueueFamilyIndex) >= len(families) {
+			return vk.ErrorInitializationFailed
+		}
+	}
+	device := vk.Device(d.create("Device"))
+	if d.devices == nil {
+		d.devices = make(map[vk.Device]int)
+	}
+	d.devices[device] = d.gpus[physicalDevice]
+	*pDevice = device
+	return vk.Success
+}
+
+func (d *Driver) DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyDevice")
+	d.destroy(unsafe.Pointer(device))
+	delete(d.devices, device)
+}
+
+func (d *Driver) DeviceWaitIdle(device vk.Device) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("DeviceWaitIdle")
+}
+
+func (d *Driver) GetDeviceQueue(device vk.Device, queueFamilyIndex uint32, queueIndex uint32, pQueue *vk.Queue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetDeviceQueue")
+	// queues are owned by the device, so they are not tracked as objects
+	*pQueue = vk.Queue(newHandle())
+}
+
+func (d *Driver) QueueSubmit(queue vk.Queue, submitCount uint32, pSubmits []vk.SubmitInfo, fence vk.Fence) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("QueueSubmit"); ret != vk.Success {
+		return ret
+	}
+	// the work completes immediately
+	if fence != vk.NullFence {
+		d.fences[fence] = true
+	}
+	return vk.Success
+}
+
+func (d *Driver) QueueWaitIdle(queue vk.Queue) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("QueueWaitIdle")
+}
+
+func (d *Driver) QueuePresent(queue vk.Queue, pPresentInfo *vk.PresentInfo) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("QueuePresent"); ret != vk.Success {
+		return ret
+	}
+	if len(d.PresentResults) > 0 {
+		ret := d.PresentResults[0]
+		d.PresentResults = d.PresentResults[1:]
+		return ret
+	}
+	return vk.Success
+}
+
+func (d *Driver) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateSwapchain"); ret != vk.Success {
+		return ret
+	}
+	extent := pCreateInfo.ImageExtent
+	if extent.Width == 0 || extent.Height == 0 || pCreateInfo.MinImageCount == 0 {
+		// invalid usage, real drivers may crash
+		return vk.ErrorInitializationFailed
+	}
+	sc := &swapchain{}
+	for i := uint32(0); i < pCreateInfo.MinImageCount; i++ {
+		// swapchain images are owned by the swapchain, so they are not tracked as objects
+		sc.images = append(sc.images, vk.Image(newHandle()))
+	}
+	if d.swapchains == nil {
+		d.swapchains = make(map[vk.Swapchain]*swapchain)
+	}
+	h := vk.Swapchain(d.create("Swapchain"))
+	d.swapchains[h] = sc
+	*pSwapchain = h
+	return vk.Success
+}
+
+func (d *Driver) DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroySwapchain")
+	d.destroy(unsafe.Pointer(swapchain))
+	delete(d.swapchains, swapchain)
+}
+
+func (d *Driver) GetSwapchainImages(device vk.Device, swapchain vk.Swapchain, pSwapchainImageCount *uint32,
+	pSwapchainImages []vk.Image) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("GetSwapchainImages"); ret != vk.Success {
+		return ret
+	}
+	sc, ok := d.swapchains[swapchain]
+	if !ok {
+		return vk.ErrorInitializationFailed
+	}
+	var fill func(i int)
+	if pSwapchainImages != nil {
+		fill = func(i int) {
+			pSwapchainImages[i] = sc.images[i]
+		}
+	}
+	return enumerate(pSwapchainImageCount, len(sc.images), fill)
+}
+
+func (d *Driver) AcquireNextImage(device vk.Device, swapchain vk.Swapchain, timeout uint64,
+	semaphore vk.Semaphore, fence vk.Fence, pImageIndex *uint32) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("AcquireNextImage"); ret != vk.Success {
+		return ret
+	}
+	ret := vk.Success
+	if len(d.AcquireResults) > 0 {
+		ret = d.AcquireResults[0]
+		d.AcquireResults = d.AcquireResults[1:]
+	}
+	if ret != vk.Success && ret != vk.Suboptimal {
+		return ret
+	}
+	sc, ok := d.swapchains[swapchain]
+	if !ok {
+		return vk.ErrorSurfaceLost
+	}
+	*pImageIndex = uint32(sc.next)
+	sc.next = (sc.next + 1) % len(sc.images)
+	if fence != vk.NullFence {
+		d.fences[fence] = true
+	}
+	return ret
+}
+
+func (d *Driver) CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateSemaphore"); ret != vk.Success {
+		return ret
+	}
+	*pSemaphore = vk.Semaphore(d.create("Semaphore"))
+	return vk.Success
+}
+
+func (d *Driver) DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroySemaphore")
+	d.destroy(unsafe.Pointer(semaphore))
+}
+
+func (d *Driver) CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateFence"); ret != vk.Success {
+		return ret
+	}
+	fence := vk.Fence(d.create("Fence"))
+	if d.fences == nil {
+		d.fences = make(map[vk.Fence]bool)
+	}
+	d.fences[fence] = pCreateInfo.Flags&vk.FenceCreateFlags(vk.FenceCreateSignaledBit) != 0
+	*pFence = fence
+	return vk.Success
+}
+
+func (d *Driver) DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyFence")
+	d.destroy(unsafe.Pointer(fence))
+	delete(d.fences, fence)
+}
+
+func (d *Driver) ResetFences(device vk.Device, fenceCount uint32, pFences []vk.Fence) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("ResetFences"); ret != vk.Success {
+		return ret
+	}
+	for _, fence := range pFences[:fenceCount] {
+		d.fences[fence] = false
+	}
+	return vk.Success
+}
+
+func (d *Driver) GetFenceStatus(device vk.Device, fence vk.Fence) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("GetFenceStatus"); ret != vk.Success {
+		return ret
+	}
+	if d.fences[fence] {
+		return vk.Success
+	}
+	return vk.NotReady
+}
+
+func (d *Driver) WaitForFences(device vk.Device, fenceCount uint32, pFences []vk.Fence,
+	waitAll vk.Bool32, timeout uint64) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("WaitForFences"); ret != vk.Success {
+		return ret
+	}
+	signaled := 0
+	for _, fence := range pFences[:fenceCount] {
+		if d.fences[fence] {
+			signaled++
+		}
+	}
+	if signaled == int(fenceCount) || (waitAll == vk.False && signaled > 0) {
+		return vk.Success
+	}
+	// nothing would ever signal the fences
+	return vk.Timeout
+}
+
+func (d *Driver) CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateCommandPool"); ret != vk.Success {
+		return ret
+	}
+	*pCommandPool = vk.CommandPool(d.create("CommandPool"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyCommandPool")
+	d.destroy(unsafe.Pointer(commandPool))
+}
+
+func (d *Driver) ResetCommandPool(device vk.Device, commandPool vk.CommandPool, flags vk.CommandPoolResetFlags) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("ResetCommandPool")
+}
+
+func (d *Driver) AllocateCommandBuffers(device vk.Device, pAllocateInfo *vk.CommandBufferAllocateInfo,
+	pCommandBuffers []vk.CommandBuffer) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("AllocateCommandBuffers"); ret != vk.Success {
+		return ret
+	}
+	// command buffers are freed along with the pool, so they are not tracked as objects
+	for i := uint32(0); i < pAllocateInfo.CommandBufferCount; i++ {
+		pCommandBuffers[i] = vk.CommandBuffer(newHandle())
+	}
+	return vk.Success
+}
+
+func (d *Driver) FreeCommandBuffers(device vk.Device, commandPool vk.CommandPool, commandBufferCount uint32,
+	pCommandBuffers []vk.CommandBuffer) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("FreeCommandBuffers")
+}
+
+func (d *Driver) BeginCommandBuffer(commandBuffer vk.CommandBuffer, pBeginInfo *vk.CommandBufferBeginInfo) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("BeginCommandBuffer")
+}
+
+func (d *Driver) EndCommandBuffer(commandBuffer vk.CommandBuffer) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("EndCommandBuffer")
+}
+
+func (d *Driver) ResetCommandBuffer(commandBuffer vk.CommandBuffer, flags vk.CommandBufferResetFlags) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("ResetCommandBuffer")
+}
+
+func (d *Driver) CmdPipelineBarrier(commandBuffer vk.CommandBuffer, srcStageMask vk.PipelineStageFlags,
+	dstStageMask vk.PipelineStageFlags, dependencyFlags vk.DependencyFlags,
+	memoryBarrierCount uint32, pMemoryBarriers []vk.MemoryBarrier,
+	bufferMemoryBarrierCount uint32, pBufferMemoryBarriers []vk.BufferMemoryBarrier,
+	imageMemoryBarrierCount uint32, pImageMemoryBarriers []vk.ImageMemoryBarrier) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdPipelineBarrier")
+}
+
+func (d *Driver) CmdBeginRenderPass(commandBuffer vk.CommandBuffer, pRenderPassBegin *vk.RenderPassBeginInfo,
+	contents vk.SubpassContents) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdBeginRenderPass")
+}
+
+func (d *Driver) CmdExecuteCommands(commandBuffer vk.CommandBuffer, commandBufferCount uint32,
+	pCommandBuffers []vk.CommandBuffer) {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdExecuteCommands")
+}
+
+func (d *Driver) CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateImage"); ret != vk.Success {
+		return ret
+	}
+	h := d.create("Image")
+	extent := pCreateInfo.Extent
+	d.setSize(h, vk.DeviceSize(extent.Width*extent.Height*extent.Depth*4))
+	*pImage = vk.Image(h)
+	return vk.Success
+}
+
+func (d *Driver) DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyImage")
+	d.destroy(unsafe.Pointer(image))
+}
+
+func (d *Driver) GetImageMemoryRequirements(device vk.Device, image vk.Image, pMemoryRequirements *vk.MemoryRequirements) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetImageMemoryRequirements")
+	*pMemoryRequirements = d.memoryRequirements(device, unsafe.Pointer(image))
+}
+
+func (d *Driver) BindImageMemory(device vk.Device, image vk.Image, memory vk.DeviceMemory,
+	memoryOffset vk.DeviceSize) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("BindImageMemory")
+}
+
+func (d *Driver) CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateImageView"); ret != vk.Success {
+		return ret
+	}
+	*pView = vk.ImageView(d.create("ImageView"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyImageView")
+	d.destroy(unsafe.Pointer(imageView))
+}
+
+func (d *Driver) CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateBuffer"); ret != vk.Success {
+		return ret
+	}
+	h := d.create("Buffer")
+	d.setSize(h, pCreateInfo.Size)
+	*pBuffer = vk.Buffer(h)
+	return vk.Success
+}
+
+func (d *Driver) DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyBuffer")
+	d.destroy(unsafe.Pointer(buffer))
+}
+
+func (d *Driver) GetBufferMemoryRequirements(device vk.Device, buffer vk.Buffer, pMemoryRequirements *vk.MemoryRequirements) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("GetBufferMemoryRequirements")
+	*pMemoryRequirements = d.memoryRequirements(device, unsafe.Pointer(buffer))
+}
+
+// memoryRequirements allows any memory type of the device, d.mu must be held.
+func (d *Driver) memoryRequirements(device vk.Device, resource unsafe.Pointer) vk.MemoryRequirements {
+	typeCount := d.deviceGPU(device).MemoryProperties.MemoryTypeCount
+	return vk.MemoryRequirements{
+		Size:           d.sizes[resource],
+		Alignment:      256,
+		MemoryTypeBits: uint32(1<<typeCount - 1),
+	}
+}
+
+func (d *Driver) BindBufferMemory(device vk.Device, buffer vk.Buffer, memory vk.DeviceMemory,
+	memoryOffset vk.DeviceSize) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.call("BindBufferMemory")
+}
+
+func (d *Driver) AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo,
+	pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("AllocateMemory"); ret != vk.Success {
+		return ret
+	}
+	if pAllocateInfo.MemoryTypeIndex >= d.deviceGPU(device).MemoryProperties.MemoryTypeCount {
+		return vk.ErrorOutOfDeviceMemory
+	}
+	memory := vk.DeviceMemory(d.create("DeviceMemory"))
+	if d.memory == nil {
+		d.memory = make(map[vk.DeviceMemory][]byte)
+	}
+	d.memory[memory] = make([]byte, pAllocateInfo.AllocationSize)
+	*pMemory = memory
+	return vk.Success
+}
+
+func (d *Driver) FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("FreeMemory")
+	d.destroy(unsafe.Pointer(memory))
+	delete(d.memory, memory)
+}
+
+func (d *Driver) MapMemory(device vk.Device, memory vk.DeviceMemory, offset vk.DeviceSize, size vk.DeviceSize,
+	flags vk.MemoryMapFlags, ppData *unsafe.Pointer) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("MapMemory"); ret != vk.Success {
+		return ret
+	}
+	data := d.memory[memory]
+	if int(offset) >= len(data) {
+		return vk.ErrorMemoryMapFailed
+	}
+	*ppData = unsafe.Pointer(&data[offset])
+	return vk.Success
+}
+
+func (d *Driver) UnmapMemory(device vk.Device, memory vk.DeviceMemory) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("UnmapMemory")
+}
+
+// Memory gets the contents of the device memory, e.g. to check uploads.
+func (d *Driver) Memory(memory vk.DeviceMemory) []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.memory[memory]
+}
+
+func (d *Driver) CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateShaderModule"); ret != vk.Success {
+		return ret
+	}
+	*pShaderModule = vk.ShaderModule(d.create("ShaderModule"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyShaderModule")
+	d.destroy(unsafe.Pointer(shaderModule))
+}
+
+func (d *Driver) CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateRenderPass"); ret != vk.Success {
+		return ret
+	}
+	*pRenderPass = vk.RenderPass(d.create("RenderPass"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyRenderPass")
+	d.destroy(unsafe.Pointer(renderPass))
+}
+
+func (d *Driver) CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("CreateFramebuffer"); ret != vk.Success {
+		return ret
+	}
+	*pFramebuffer = vk.Framebuffer(d.create("Framebuffer"))
+	return vk.Success
+}
+
+func (d *Driver) DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("DestroyFramebuffer")
+	d.destroy(unsafe.Pointer(framebuffer))
+}
+
+func (d *Driver) SetDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if ret := d.call("SetDebugUtilsObjectName"); ret != vk.Success {
+		return ret
+	}
+	if d.names == nil {
+		d.names = make(map[uint64]string)
+	}
+	d.names[pNameInfo.ObjectHandle] = strings.TrimSuffix(pNameInfo.PObjectName, "\x00")
+	return vk.Success
+}
+
+// ObjectName gets the debug name of the object handle set with SetDebugUtilsObjectName.
+func (d *Driver) ObjectName(handle uint64) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.names[handle]
+}
+
+func (d *Driver) CmdBeginDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdBeginDebugUtilsLabel")
+}
+
+func (d *Driver) CmdEndDebugUtilsLabel(commandBuffer vk.CommandBuffer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdEndDebugUtilsLabel")
+}
+
+func (d *Driver) CmdInsertDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("CmdInsertDebugUtilsLabel")
+}
+
+func (d *Driver) QueueBeginDebugUtilsLabel(queue vk.Queue, pLabelInfo *vk.DebugUtilsLabel) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("QueueBeginDebugUtilsLabel")
+}
+
+func (d *Driver) QueueEndDebugUtilsLabel(queue vk.Queue) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.call("QueueEndDebugUtilsLabel")
+}
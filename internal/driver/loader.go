@@ -0,0 +1,230 @@
+package driver
+
+/*
+#cgo linux freebsd LDFLAGS: -ldl
+
+#include <stddef.h>
+#include <stdint.h>
+#include <stdlib.h>
+
+#if defined(_WIN32)
+#include <windows.h>
+#else
+#include <dlfcn.h>
+#endif
+
+typedef void (*asche_void_function)(void);
+typedef asche_void_function (*asche_get_instance_proc_addr)(void *instance, const char *name);
+
+// asche_load_get_instance_proc_addr finds vkGetInstanceProcAddr of the Vulkan loader
+// used by the process, the loader library is opened again if it's not linked in.
+static asche_get_instance_proc_addr asche_load_get_instance_proc_addr(void) {
+#if defined(_WIN32)
+	HMODULE lib = LoadLibraryA("vulkan-1.dll");
+	if (lib == NULL) {
+		return NULL;
+	}
+	return (asche_get_instance_proc_addr)GetProcAddress(lib, "vkGetInstanceProcAddr");
+#else
+	void *fn = dlsym(RTLD_DEFAULT, "vkGetInstanceProcAddr");
+	if (fn != NULL) {
+		return (asche_get_instance_proc_addr)fn;
+	}
+	static const char *names[] = {
+#if defined(__APPLE__)
+		"libvulkan.1.dylib", "libMoltenVK.dylib",
+#else
+		"libvulkan.so.1", "libvulkan.so",
+#endif
+	};
+	for (size_t i = 0; i < sizeof(names) / sizeof(names[0]); i++) {
+		void *lib = dlopen(names[i], RTLD_NOW | RTLD_LOCAL);
+		if (lib == NULL) {
+			continue;
+		}
+		fn = dlsym(lib, "vkGetInstanceProcAddr");
+		if (fn != NULL) {
+			return (asche_get_instance_proc_addr)fn;
+		}
+	}
+	return NULL;
+#endif
+}
+
+static asche_void_function asche_get_proc(asche_get_instance_proc_addr gipa, void *instance, const char *name) {
+	return gipa(instance, name);
+}
+
+static int32_t asche_enumerate_instance_version(asche_void_function fn, uint32_t *version) {
+	return ((int32_t (*)(uint32_t *))fn)(version);
+}
+
+#define ASCHE_STRUCTURE_TYPE_DEBUG_UTILS_OBJECT_NAME_INFO 1000128000
+#define ASCHE_STRUCTURE_TYPE_DEBUG_UTILS_LABEL 1000128002
+
+// asche_debug_utils_label mirrors VkDebugUtilsLabelEXT.
+typedef struct {
+	int32_t sType;
+	const void *pNext;
+	const char *pLabelName;
+	float color[4];
+} asche_debug_utils_label;
+
+// asche_debug_utils_object_name_info mirrors VkDebugUtilsObjectNameInfoEXT.
+typedef struct {
+	int32_t sType;
+	const void *pNext;
+	int32_t objectType;
+	uint64_t objectHandle;
+	const char *pObjectName;
+} asche_debug_utils_object_name_info;
+
+// asche_begin_label calls vkCmdBeginDebugUtilsLabelEXT, vkCmdInsertDebugUtilsLabelEXT
+// or vkQueueBeginDebugUtilsLabelEXT, which take the same arguments.
+static void asche_begin_label(asche_void_function fn, void *handle, const char *name, const float *color) {
+	asche_debug_utils_label label = {ASCHE_STRUCTURE_TYPE_DEBUG_UTILS_LABEL, NULL, name,
+		{color[0], color[1], color[2], color[3]}};
+	((void (*)(void *, const asche_debug_utils_label *))fn)(handle, &label);
+}
+
+// asche_end_label calls vkCmdEndDebugUtilsLabelEXT or vkQueueEndDebugUtilsLabelEXT.
+static void asche_end_label(asche_void_function fn, void *handle) {
+	((void (*)(void *))fn)(handle);
+}
+
+static int32_t asche_set_object_name(asche_void_function fn, void *device,
+	int32_t object_type, uint64_t object_handle, const char *name) {
+
+	asche_debug_utils_object_name_info info = {ASCHE_STRUCTURE_TYPE_DEBUG_UTILS_OBJECT_NAME_INFO, NULL,
+		object_type, object_handle, name};
+	return ((int32_t (*)(void *, const asche_debug_utils_object_name_info *))fn)(device, &info);
+}
+*/
+import "C"
+
+import (
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// loader resolves the entry points vulkan-go doesn't bind through vkGetInstanceProcAddr,
+// the ones that are not available are nil.
+var loader struct {
+	once                     sync.Once
+	getInstanceProcAddr      C.asche_get_instance_proc_addr
+	enumerateInstanceVersion C.asche_void_function
+}
+
+func loadGlobalProcs() {
+	loader.once.Do(func() {
+		loader.getInstanceProcAddr = C.asche_load_get_instance_proc_addr()
+		loader.enumerateInstanceVersion = getInstanceProc(nil, "vkEnumerateInstanceVersion")
+	})
+}
+
+// getInstanceProc gets an entry point of the instance, or a global one if the instance is nil.
+func getInstanceProc(instance unsafe.Pointer, name string) C.asche_void_function {
+	if loader.getInstanceProcAddr == nil {
+		return nil
+	}
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.asche_get_proc(loader.getInstanceProcAddr, instance, cname)
+}
+
+// enumerateInstanceVersion reports Vulkan 1.0 if the loader doesn't provide vkEnumerateInstanceVersion,
+// as 1.0 loaders don't.
+func enumerateInstanceVersion(pApiVersion *uint32) vk.Result {
+	loadGlobalProcs()
+	if loader.enumerateInstanceVersion == nil {
+		*pApiVersion = vk.MakeVersion(1, 0, 0)
+		return vk.Success
+	}
+	var version C.uint32_t
+	ret := vk.Result(C.asche_enumerate_instance_version(loader.enumerateInstanceVersion, &version))
+	*pApiVersion = uint32(version)
+	return ret
+}
+
+// debugUtilsProcs are the VK_EXT_debug_utils entry points of an instance.
+type debugUtilsProcs struct {
+	setObjectName  C.asche_void_function
+	cmdBeginLabel  C.asche_void_function
+	cmdEndLabel    C.asche_void_function
+	cmdInsertLabel C.asche_void_function
+	queueBegin     C.asche_void_function
+	queueEnd       C.asche_void_function
+}
+
+// debugUtils is nil until an instance providing VK_EXT_debug_utils is initialized,
+// the calls do nothing meanwhile. The entry points dispatch on their first argument,
+// so the ones of any instance serve all of them.
+var debugUtils atomic.Pointer[debugUtilsProcs]
+
+func loadInstanceProcs(instance vk.Instance) {
+	loadGlobalProcs()
+	procs := &debugUtilsProcs{
+		setObjectName:  getInstanceProc(unsafe.Pointer(instance), "vkSetDebugUtilsObjectNameEXT"),
+		cmdBeginLabel:  getInstanceProc(unsafe.Pointer(instance), "vkCmdBeginDebugUtilsLabelEXT"),
+		cmdEndLabel:    getInstanceProc(unsafe.Pointer(instance), "vkCmdEndDebugUtilsLabelEXT"),
+		cmdInsertLabel: getInstanceProc(unsafe.Pointer(instance), "vkCmdInsertDebugUtilsLabelEXT"),
+		queueBegin:     getInstanceProc(unsafe.Pointer(instance), "vkQueueBeginDebugUtilsLabelEXT"),
+		queueEnd:       getInstanceProc(unsafe.Pointer(instance), "vkQueueEndDebugUtilsLabelEXT"),
+	}
+	if procs.setObjectName == nil || procs.cmdBeginLabel == nil || procs.cmdEndLabel == nil ||
+		procs.cmdInsertLabel == nil || procs.queueBegin == nil || procs.queueEnd == nil {
+		return
+	}
+	debugUtils.Store(procs)
+}
+
+func beginLabel(fn C.asche_void_function, handle unsafe.Pointer, pLabelInfo *vk.DebugUtilsLabel) {
+	name := C.CString(pLabelInfo.PLabelName)
+	defer C.free(unsafe.Pointer(name))
+	color := pLabelInfo.Color
+	C.asche_begin_label(fn, handle, name, (*C.float)(unsafe.Pointer(&color[0])))
+}
+
+func cmdBeginDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	if procs := debugUtils.Load(); procs != nil {
+		beginLabel(procs.cmdBeginLabel, unsafe.Pointer(commandBuffer), pLabelInfo)
+	}
+}
+
+func cmdEndDebugUtilsLabel(commandBuffer vk.CommandBuffer) {
+	if procs := debugUtils.Load(); procs != nil {
+		C.asche_end_label(procs.cmdEndLabel, unsafe.Pointer(commandBuffer))
+	}
+}
+
+func cmdInsertDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	if procs := debugUtils.Load(); procs != nil {
+		beginLabel(procs.cmdInsertLabel, unsafe.Pointer(commandBuffer), pLabelInfo)
+	}
+}
+
+func queueBeginDebugUtilsLabel(queue vk.Queue, pLabelInfo *vk.DebugUtilsLabel) {
+	if procs := debugUtils.Load(); procs != nil {
+		beginLabel(procs.queueBegin, unsafe.Pointer(queue), pLabelInfo)
+	}
+}
+
+func queueEndDebugUtilsLabel(queue vk.Queue) {
+	if procs := debugUtils.Load(); procs != nil {
+		C.asche_end_label(procs.queueEnd, unsafe.Pointer(queue))
+	}
+}
+
+func setDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result {
+	procs := debugUtils.Load()
+	if procs == nil {
+		return vk.Success
+	}
+	name := C.CString(pNameInfo.PObjectName)
+	defer C.free(unsafe.Pointer(name))
+	return vk.Result(C.asche_set_object_name(procs.setObjectName, unsafe.Pointer(device),
+		C.int32_t(pNameInfo.ObjectType), C.uint64_t(pNameInfo.ObjectHandle), name))
+}
@@ -0,0 +1,550 @@
+package driver
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/trace"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Tracing is a Driver that writes every call made through it to a trace.
+type Tracing struct {
+	Driver Driver
+	Trace  *trace.Writer
+}
+
+// record writes a call, args are pairs of names and values.
+func (t Tracing) record(name string, start time.Time, ret *vk.Result, err error, args ...interface{}) {
+	c := &trace.Call{
+		Time:      start.Sub(t.Trace.Start()),
+		Duration:  time.Since(start),
+		Goroutine: goroutineID(),
+		Func:      name,
+		Args:      make([]string, 0, len(args)/2),
+	}
+	if ret != nil {
+		c.Result = int32(*ret)
+		c.HasResult = true
+		if *ret != vk.Success {
+			c.ResultName = vk.Error(*ret).Error()
+		}
+	}
+	if err != nil {
+		c.Err = err.Error()
+	}
+	for i := 0; i+1 < len(args); i += 2 {
+		c.Args = append(c.Args, fmt.Sprintf("%s=%s", args[i], formatArg(args[i+1])))
+	}
+	// failing to trace must not affect the app, the error is reported by Flush
+	t.Trace.Write(c)
+}
+
+var _ Driver = Tracing{}
+
+// formatArg formats handles as addresses and strips the terminators of C strings.
+func formatArg(v interface{}) string {
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "null"
+		}
+		return fmt.Sprintf("%#x", rv.Pointer())
+	}
+	return strings.Replace(fmt.Sprint(v), "\x00", "", -1)
+}
+
+// goroutineID parses the id of the current goroutine out of its stack trace.
+func goroutineID() int64 {
+	var buf [64]byte
+	s := string(buf[:runtime.Stack(buf[:], false)])
+	s = strings.TrimPrefix(s, "goroutine ")
+	if i := strings.IndexByte(s, ' '); i > 0 {
+		s = s[:i]
+	}
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
+}
+
+func (t Tracing) AcquireNextImage(device vk.Device, swapchain vk.Swapchain, timeout uint64, semaphore vk.Semaphore, fence vk.Fence, pImageIndex *uint32) vk.Result {
+	start := time.Now()
+	ret := t.Driver.AcquireNextImage(device, swapchain, timeout, semaphore, fence, pImageIndex)
+	t.record("AcquireNextImage", start, &ret, nil, "device", device, "swapchain", swapchain, "timeout", timeout, "semaphore", semaphore, "fence", fence, "imageIndex", *pImageIndex)
+	return ret
+}
+
+func (t Tracing) AllocateCommandBuffers(device vk.Device, pAllocateInfo *vk.CommandBufferAllocateInfo, pCommandBuffers []vk.CommandBuffer) vk.Result {
+	start := time.Now()
+	ret := t.Driver.AllocateCommandBuffers(device, pAllocateInfo, pCommandBuffers)
+	t.record("AllocateCommandBuffers", start, &ret, nil, "device", device, "level", pAllocateInfo.Level, "count", pAllocateInfo.CommandBufferCount)
+	return ret
+}
+
+func (t Tracing) AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo, pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result {
+	start := time.Now()
+	ret := t.Driver.AllocateMemory(device, pAllocateInfo, pAllocator, pMemory)
+	t.record("AllocateMemory", start, &ret, nil, "device", device, "size", pAllocateInfo.AllocationSize, "memoryType", pAllocateInfo.MemoryTypeIndex, "memory", *pMemory)
+	return ret
+}
+
+func (t Tracing) BeginCommandBuffer(commandBuffer vk.CommandBuffer, pBeginInfo *vk.CommandBufferBeginInfo) vk.Result {
+	start := time.Now()
+	ret := t.Driver.BeginCommandBuffer(commandBuffer, pBeginInfo)
+	t.record("BeginCommandBuffer", start, &ret, nil, "commandBuffer", commandBuffer)
+	return ret
+}
+
+func (t Tracing) BindBufferMemory(device vk.Device, buffer vk.Buffer, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result {
+	start := time.Now()
+	ret := t.Driver.BindBufferMemory(device, buffer, memory, memoryOffset)
+	t.record("BindBufferMemory", start, &ret, nil, "device", device, "buffer", buffer, "memory", memory, "memoryOffset", memoryOffset)
+	return ret
+}
+
+func (t Tracing) BindImageMemory(device vk.Device, image vk.Image, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result {
+	start := time.Now()
+	ret := t.Driver.BindImageMemory(device, image, memory, memoryOffset)
+	t.record("BindImageMemory", start, &ret, nil, "device", device, "image", image, "memory", memory, "memoryOffset", memoryOffset)
+	return ret
+}
+
+func (t Tracing) CmdBeginDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	start := time.Now()
+	t.Driver.CmdBeginDebugUtilsLabel(commandBuffer, pLabelInfo)
+	t.record("CmdBeginDebugUtilsLabel", start, nil, nil, "commandBuffer", commandBuffer, "label", pLabelInfo.PLabelName)
+}
+
+func (t Tracing) CmdBeginRenderPass(commandBuffer vk.CommandBuffer, pRenderPassBegin *vk.RenderPassBeginInfo, contents vk.SubpassContents) {
+	start := time.Now()
+	t.Driver.CmdBeginRenderPass(commandBuffer, pRenderPassBegin, contents)
+	t.record("CmdBeginRenderPass", start, nil, nil, "commandBuffer", commandBuffer, "contents", contents)
+}
+
+func (t Tracing) CmdEndDebugUtilsLabel(commandBuffer vk.CommandBuffer) {
+	start := time.Now()
+	t.Driver.CmdEndDebugUtilsLabel(commandBuffer)
+	t.record("CmdEndDebugUtilsLabel", start, nil, nil, "commandBuffer", commandBuffer)
+}
+
+func (t Tracing) CmdExecuteCommands(commandBuffer vk.CommandBuffer, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer) {
+	start := time.Now()
+	t.Driver.CmdExecuteCommands(commandBuffer, commandBufferCount, pCommandBuffers)
+	t.record("CmdExecuteCommands", start, nil, nil, "commandBuffer", commandBuffer, "commandBufferCount", commandBufferCount)
+}
+
+func (t Tracing) CmdInsertDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	start := time.Now()
+	t.Driver.CmdInsertDebugUtilsLabel(commandBuffer, pLabelInfo)
+	t.record("CmdInsertDebugUtilsLabel", start, nil, nil, "commandBuffer", commandBuffer, "label", pLabelInfo.PLabelName)
+}
+
+func (t Tracing) CmdPipelineBarrier(commandBuffer vk.CommandBuffer, srcStageMask vk.PipelineStageFlags, dstStageMask vk.PipelineStageFlags, dependencyFlags vk.DependencyFlags, memoryBarrierCount uint32, pMemoryBarriers []vk.MemoryBarrier, bufferMemoryBarrierCount uint32, pBufferMemoryBarriers []vk.BufferMemoryBarrier, imageMemoryBarrierCount uint32, pImageMemoryBarriers []vk.ImageMemoryBarrier) {
+	start := time.Now()
+	t.Driver.CmdPipelineBarrier(commandBuffer, srcStageMask, dstStageMask, dependencyFlags, memoryBarrierCount, pMemoryBarriers, bufferMemoryBarrierCount, pBufferMemoryBarriers, imageMemoryBarrierCount, pImageMemoryBarriers)
+	t.record("CmdPipelineBarrier", start, nil, nil, "commandBuffer", commandBuffer, "srcStageMask", srcStageMask, "dstStageMask", dstStageMask, "dependencyFlags", dependencyFlags, "memoryBarrierCount", memoryBarrierCount, "bufferMemoryBarrierCount", bufferMemoryBarrierCount, "imageMemoryBarrierCount", imageMemoryBarrierCount)
+}
+
+func (t Tracing) CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo, pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateBuffer(device, pCreateInfo, pAllocator, pBuffer)
+	t.record("CreateBuffer", start, &ret, nil, "device", device, "size", pCreateInfo.Size, "usage", pCreateInfo.Usage, "buffer", *pBuffer)
+	return ret
+}
+
+func (t Tracing) CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo, pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateCommandPool(device, pCreateInfo, pAllocator, pCommandPool)
+	t.record("CreateCommandPool", start, &ret, nil, "device", device, "commandPool", *pCommandPool)
+	return ret
+}
+
+func (t Tracing) CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo, pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateDebugReportCallback(instance, pCreateInfo, pAllocator, pCallback)
+	t.record("CreateDebugReportCallback", start, &ret, nil, "instance", instance, "callback", *pCallback)
+	return ret
+}
+
+func (t Tracing) CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo, pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateDevice(physicalDevice, pCreateInfo, pAllocator, pDevice)
+	t.record("CreateDevice", start, &ret, nil, "extensions", pCreateInfo.PpEnabledExtensionNames, "device", *pDevice)
+	return ret
+}
+
+func (t Tracing) CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo, pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateFence(device, pCreateInfo, pAllocator, pFence)
+	t.record("CreateFence", start, &ret, nil, "device", device, "fence", *pFence)
+	return ret
+}
+
+func (t Tracing) CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo, pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateFramebuffer(device, pCreateInfo, pAllocator, pFramebuffer)
+	t.record("CreateFramebuffer", start, &ret, nil, "device", device, "framebuffer", *pFramebuffer)
+	return ret
+}
+
+func (t Tracing) CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo, pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateImage(device, pCreateInfo, pAllocator, pImage)
+	t.record("CreateImage", start, &ret, nil, "device", device, "format", pCreateInfo.Format, "extent", pCreateInfo.Extent, "samples", pCreateInfo.Samples, "image", *pImage)
+	return ret
+}
+
+func (t Tracing) CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo, pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateImageView(device, pCreateInfo, pAllocator, pView)
+	t.record("CreateImageView", start, &ret, nil, "device", device, "view", *pView)
+	return ret
+}
+
+func (t Tracing) CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks, pInstance *vk.Instance) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateInstance(pCreateInfo, pAllocator, pInstance)
+	t.record("CreateInstance", start, &ret, nil, "extensions", pCreateInfo.PpEnabledExtensionNames, "layers", pCreateInfo.PpEnabledLayerNames, "instance", *pInstance)
+	return ret
+}
+
+func (t Tracing) CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo, pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateRenderPass(device, pCreateInfo, pAllocator, pRenderPass)
+	t.record("CreateRenderPass", start, &ret, nil, "device", device, "renderPass", *pRenderPass)
+	return ret
+}
+
+func (t Tracing) CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo, pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateSemaphore(device, pCreateInfo, pAllocator, pSemaphore)
+	t.record("CreateSemaphore", start, &ret, nil, "device", device, "semaphore", *pSemaphore)
+	return ret
+}
+
+func (t Tracing) CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo, pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateShaderModule(device, pCreateInfo, pAllocator, pShaderModule)
+	t.record("CreateShaderModule", start, &ret, nil, "device", device, "shaderModule", *pShaderModule)
+	return ret
+}
+
+func (t Tracing) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo, pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+	start := time.Now()
+	ret := t.Driver.CreateSwapchain(device, pCreateInfo, pAllocator, pSwapchain)
+	t.record("CreateSwapchain", start, &ret, nil, "device", device, "format", pCreateInfo.ImageFormat, "extent", pCreateInfo.ImageExtent, "minImageCount", pCreateInfo.MinImageCount, "presentMode", pCreateInfo.PresentMode, "oldSwapchain", pCreateInfo.OldSwapchain, "swapchain", *pSwapchain)
+	return ret
+}
+
+func (t Tracing) DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyBuffer(device, buffer, pAllocator)
+	t.record("DestroyBuffer", start, nil, nil, "device", device, "buffer", buffer)
+}
+
+func (t Tracing) DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyCommandPool(device, commandPool, pAllocator)
+	t.record("DestroyCommandPool", start, nil, nil, "device", device, "commandPool", commandPool)
+}
+
+func (t Tracing) DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyDebugReportCallback(instance, callback, pAllocator)
+	t.record("DestroyDebugReportCallback", start, nil, nil, "instance", instance, "callback", callback)
+}
+
+func (t Tracing) DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyDevice(device, pAllocator)
+	t.record("DestroyDevice", start, nil, nil, "device", device)
+}
+
+func (t Tracing) DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyFence(device, fence, pAllocator)
+	t.record("DestroyFence", start, nil, nil, "device", device, "fence", fence)
+}
+
+func (t Tracing) DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyFramebuffer(device, framebuffer, pAllocator)
+	t.record("DestroyFramebuffer", start, nil, nil, "device", device, "framebuffer", framebuffer)
+}
+
+func (t Tracing) DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyImage(device, image, pAllocator)
+	t.record("DestroyImage", start, nil, nil, "device", device, "image", image)
+}
+
+func (t Tracing) DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyImageView(device, imageView, pAllocator)
+	t.record("DestroyImageView", start, nil, nil, "device", device, "imageView", imageView)
+}
+
+func (t Tracing) DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyInstance(instance, pAllocator)
+	t.record("DestroyInstance", start, nil, nil, "instance", instance)
+}
+
+func (t Tracing) DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyRenderPass(device, renderPass, pAllocator)
+	t.record("DestroyRenderPass", start, nil, nil, "device", device, "renderPass", renderPass)
+}
+
+func (t Tracing) DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroySemaphore(device, semaphore, pAllocator)
+	t.record("DestroySemaphore", start, nil, nil, "device", device, "semaphore", semaphore)
+}
+
+func (t Tracing) DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroyShaderModule(device, shaderModule, pAllocator)
+	t.record("DestroyShaderModule", start, nil, nil, "device", device, "shaderModule", shaderModule)
+}
+
+func (t Tracing) DestroySurface(instance vk.Instance, surface vk.Surface, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroySurface(instance, surface, pAllocator)
+	t.record("DestroySurface", start, nil, nil, "instance", instance, "surface", surface)
+}
+
+func (t Tracing) DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.DestroySwapchain(device, swapchain, pAllocator)
+	t.record("DestroySwapchain", start, nil, nil, "device", device, "swapchain", swapchain)
+}
+
+func (t Tracing) DeviceWaitIdle(device vk.Device) vk.Result {
+	start := time.Now()
+	ret := t.Driver.DeviceWaitIdle(device)
+	t.record("DeviceWaitIdle", start, &ret, nil, "device", device)
+	return ret
+}
+
+func (t Tracing) EndCommandBuffer(commandBuffer vk.CommandBuffer) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EndCommandBuffer(commandBuffer)
+	t.record("EndCommandBuffer", start, &ret, nil, "commandBuffer", commandBuffer)
+	return ret
+}
+
+func (t Tracing) EnumerateDeviceExtensionProperties(physicalDevice vk.PhysicalDevice, pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EnumerateDeviceExtensionProperties(physicalDevice, pLayerName, pPropertyCount, pProperties)
+	t.record("EnumerateDeviceExtensionProperties", start, &ret, nil, "layerName", pLayerName, "propertyCount", *pPropertyCount)
+	return ret
+}
+
+func (t Tracing) EnumerateInstanceExtensionProperties(pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EnumerateInstanceExtensionProperties(pLayerName, pPropertyCount, pProperties)
+	t.record("EnumerateInstanceExtensionProperties", start, &ret, nil, "layerName", pLayerName, "propertyCount", *pPropertyCount)
+	return ret
+}
+
+func (t Tracing) EnumerateInstanceVersion(pApiVersion *uint32) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EnumerateInstanceVersion(pApiVersion)
+	t.record("EnumerateInstanceVersion", start, &ret, nil, "apiVersion", vk.Version(*pApiVersion))
+	return ret
+}
+
+func (t Tracing) EnumerateInstanceLayerProperties(pPropertyCount *uint32, pProperties []vk.LayerProperties) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EnumerateInstanceLayerProperties(pPropertyCount, pProperties)
+	t.record("EnumerateInstanceLayerProperties", start, &ret, nil, "propertyCount", *pPropertyCount)
+	return ret
+}
+
+func (t Tracing) EnumeratePhysicalDevices(instance vk.Instance, pPhysicalDeviceCount *uint32, pPhysicalDevices []vk.PhysicalDevice) vk.Result {
+	start := time.Now()
+	ret := t.Driver.EnumeratePhysicalDevices(instance, pPhysicalDeviceCount, pPhysicalDevices)
+	t.record("EnumeratePhysicalDevices", start, &ret, nil, "instance", instance, "physicalDeviceCount", *pPhysicalDeviceCount)
+	return ret
+}
+
+func (t Tracing) FreeCommandBuffers(device vk.Device, commandPool vk.CommandPool, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer) {
+	start := time.Now()
+	t.Driver.FreeCommandBuffers(device, commandPool, commandBufferCount, pCommandBuffers)
+	t.record("FreeCommandBuffers", start, nil, nil, "device", device, "commandPool", commandPool, "commandBufferCount", commandBufferCount)
+}
+
+func (t Tracing) FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks) {
+	start := time.Now()
+	t.Driver.FreeMemory(device, memory, pAllocator)
+	t.record("FreeMemory", start, nil, nil, "device", device, "memory", memory)
+}
+
+func (t Tracing) GetBufferMemoryRequirements(device vk.Device, buffer vk.Buffer, pMemoryRequirements *vk.MemoryRequirements) {
+	start := time.Now()
+	t.Driver.GetBufferMemoryRequirements(device, buffer, pMemoryRequirements)
+	t.record("GetBufferMemoryRequirements", start, nil, nil, "device", device, "buffer", buffer)
+}
+
+func (t Tracing) GetDeviceQueue(device vk.Device, queueFamilyIndex uint32, queueIndex uint32, pQueue *vk.Queue) {
+	start := time.Now()
+	t.Driver.GetDeviceQueue(device, queueFamilyIndex, queueIndex, pQueue)
+	t.record("GetDeviceQueue", start, nil, nil, "device", device, "queueFamilyIndex", queueFamilyIndex, "queueIndex", queueIndex, "queue", *pQueue)
+}
+
+func (t Tracing) GetFenceStatus(device vk.Device, fence vk.Fence) vk.Result {
+	start := time.Now()
+	ret := t.Driver.GetFenceStatus(device, fence)
+	t.record("GetFenceStatus", start, &ret, nil, "device", device, "fence", fence)
+	return ret
+}
+
+func (t Tracing) GetImageMemoryRequirements(device vk.Device, image vk.Image, pMemoryRequirements *vk.MemoryRequirements) {
+	start := time.Now()
+	t.Driver.GetImageMemoryRequirements(device, image, pMemoryRequirements)
+	t.record("GetImageMemoryRequirements", start, nil, nil, "device", device, "image", image)
+}
+
+func (t Tracing) GetPhysicalDeviceFormatProperties(physicalDevice vk.PhysicalDevice, format vk.Format, pFormatProperties *vk.FormatProperties) {
+	start := time.Now()
+	t.Driver.GetPhysicalDeviceFormatProperties(physicalDevice, format, pFormatProperties)
+	t.record("GetPhysicalDeviceFormatProperties", start, nil, nil, "format", format)
+}
+
+func (t Tracing) GetPhysicalDeviceMemoryProperties(physicalDevice vk.PhysicalDevice, pMemoryProperties *vk.PhysicalDeviceMemoryProperties) {
+	start := time.Now()
+	t.Driver.GetPhysicalDeviceMemoryProperties(physicalDevice, pMemoryProperties)
+	t.record("GetPhysicalDeviceMemoryProperties", start, nil, nil)
+}
+
+func (t Tracing) GetPhysicalDeviceProperties(physicalDevice vk.PhysicalDevice, pProperties *vk.PhysicalDeviceProperties) {
+	start := time.Now()
+	t.Driver.GetPhysicalDeviceProperties(physicalDevice, pProperties)
+	t.record("GetPhysicalDeviceProperties", start, nil, nil)
+}
+
+func (t Tracing) GetPhysicalDeviceQueueFamilyProperties(physicalDevice vk.PhysicalDevice, pQueueFamilyPropertyCount *uint32, pQueueFamilyProperties []vk.QueueFamilyProperties) {
+	start := time.Now()
+	t.Driver.GetPhysicalDeviceQueueFamilyProperties(physicalDevice, pQueueFamilyPropertyCount, pQueueFamilyProperties)
+	t.record("GetPhysicalDeviceQueueFamilyProperties", start, nil, nil, "queueFamilyPropertyCount", *pQueueFamilyPropertyCount)
+}
+
+func (t Tracing) GetPhysicalDeviceSurfaceCapabilities(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceCapabilities *vk.SurfaceCapabilities) vk.Result {
+	start := time.Now()
+	ret := t.Driver.GetPhysicalDeviceSurfaceCapabilities(physicalDevice, surface, pSurfaceCapabilities)
+	t.record("GetPhysicalDeviceSurfaceCapabilities", start, &ret, nil, "surface", surface)
+	return ret
+}
+
+func (t Tracing) GetPhysicalDeviceSurfaceFormats(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceFormatCount *uint32, pSurfaceFormats []vk.SurfaceFormat) vk.Result {
+	start := time.Now()
+	ret := t.Driver.GetPhysicalDeviceSurfaceFormats(physicalDevice, surface, pSurfaceFormatCount, pSurfaceFormats)
+	t.record("GetPhysicalDeviceSurfaceFormats", start, &ret, nil, "surface", surface, "surfaceFormatCount", *pSurfaceFormatCount)
+	return ret
+}
+
+func (t Tracing) GetPhysicalDeviceSurfaceSupport(physicalDevice vk.PhysicalDevice, queueFamilyIndex uint32, surface vk.Surface, pSupported *vk.Bool32) vk.Result {
+	start := time.Now()
+	ret := t.Driver.GetPhysicalDeviceSurfaceSupport(physicalDevice, queueFamilyIndex, surface, pSupported)
+	t.record("GetPhysicalDeviceSurfaceSupport", start, &ret, nil, "queueFamilyIndex", queueFamilyIndex, "surface", surface, "supported", *pSupported)
+	return ret
+}
+
+func (t Tracing) GetSwapchainImages(device vk.Device, swapchain vk.Swapchain, pSwapchainImageCount *uint32, pSwapchainImages []vk.Image) vk.Result {
+	start := time.Now()
+	ret := t.Driver.GetSwapchainImages(device, swapchain, pSwapchainImageCount, pSwapchainImages)
+	t.record("GetSwapchainImages", start, &ret, nil, "device", device, "swapchain", swapchain, "swapchainImageCount", *pSwapchainImageCount)
+	return ret
+}
+
+func (t Tracing) InitInstance(instance vk.Instance) error {
+	start := time.Now()
+	err := t.Driver.InitInstance(instance)
+	t.record("InitInstance", start, nil, err, "instance", instance)
+	return err
+}
+
+func (t Tracing) MapMemory(device vk.Device, memory vk.DeviceMemory, offset vk.DeviceSize, size vk.DeviceSize, flags vk.MemoryMapFlags, ppData *unsafe.Pointer) vk.Result {
+	start := time.Now()
+	ret := t.Driver.MapMemory(device, memory, offset, size, flags, ppData)
+	t.record("MapMemory", start, &ret, nil, "device", device, "memory", memory, "offset", offset, "size", size, "flags", flags)
+	return ret
+}
+
+func (t Tracing) QueueBeginDebugUtilsLabel(queue vk.Queue, pLabelInfo *vk.DebugUtilsLabel) {
+	start := time.Now()
+	t.Driver.QueueBeginDebugUtilsLabel(queue, pLabelInfo)
+	t.record("QueueBeginDebugUtilsLabel", start, nil, nil, "queue", queue, "label", pLabelInfo.PLabelName)
+}
+
+func (t Tracing) QueueEndDebugUtilsLabel(queue vk.Queue) {
+	start := time.Now()
+	t.Driver.QueueEndDebugUtilsLabel(queue)
+	t.record("QueueEndDebugUtilsLabel", start, nil, nil, "queue", queue)
+}
+
+func (t Tracing) QueuePresent(queue vk.Queue, pPresentInfo *vk.PresentInfo) vk.Result {
+	start := time.Now()
+	ret := t.Driver.QueuePresent(queue, pPresentInfo)
+	t.record("QueuePresent", start, &ret, nil, "queue", queue, "swapchains", pPresentInfo.PSwapchains, "imageIndices", pPresentInfo.PImageIndices)
+	return ret
+}
+
+func (t Tracing) QueueSubmit(queue vk.Queue, submitCount uint32, pSubmits []vk.SubmitInfo, fence vk.Fence) vk.Result {
+	start := time.Now()
+	ret := t.Driver.QueueSubmit(queue, submitCount, pSubmits, fence)
+	t.record("QueueSubmit", start, &ret, nil, "queue", queue, "submitCount", submitCount, "fence", fence)
+	return ret
+}
+
+func (t Tracing) QueueWaitIdle(queue vk.Queue) vk.Result {
+	start := time.Now()
+	ret := t.Driver.QueueWaitIdle(queue)
+	t.record("QueueWaitIdle", start, &ret, nil, "queue", queue)
+	return ret
+}
+
+func (t Tracing) ResetCommandBuffer(commandBuffer vk.CommandBuffer, flags vk.CommandBufferResetFlags) vk.Result {
+	start := time.Now()
+	ret := t.Driver.ResetCommandBuffer(commandBuffer, flags)
+	t.record("ResetCommandBuffer", start, &ret, nil, "commandBuffer", commandBuffer, "flags", flags)
+	return ret
+}
+
+func (t Tracing) ResetCommandPool(device vk.Device, commandPool vk.CommandPool, flags vk.CommandPoolResetFlags) vk.Result {
+	start := time.Now()
+	ret := t.Driver.ResetCommandPool(device, commandPool, flags)
+	t.record("ResetCommandPool", start, &ret, nil, "device", device, "commandPool", commandPool, "flags", flags)
+	return ret
+}
+
+func (t Tracing) ResetFences(device vk.Device, fenceCount uint32, pFences []vk.Fence) vk.Result {
+	start := time.Now()
+	ret := t.Driver.ResetFences(device, fenceCount, pFences)
+	t.record("ResetFences", start, &ret, nil, "device", device, "fenceCount", fenceCount)
+	return ret
+}
+
+func (t Tracing) SetDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result {
+	start := time.Now()
+	ret := t.Driver.SetDebugUtilsObjectName(device, pNameInfo)
+	t.record("SetDebugUtilsObjectName", start, &ret, nil, "device", device, "objectType", pNameInfo.ObjectType, "objectHandle", pNameInfo.ObjectHandle, "objectName", pNameInfo.PObjectName)
+	return ret
+}
+
+func (t Tracing) UnmapMemory(device vk.Device, memory vk.DeviceMemory) {
+	start := time.Now()
+	t.Driver.UnmapMemory(device, memory)
+	t.record("UnmapMemory", start, nil, nil, "device", device, "memory", memory)
+}
+
+func (t Tracing) WaitForFences(device vk.Device, fenceCount uint32, pFences []vk.Fence, waitAll vk.Bool32, timeout uint64) vk.Result {
+	start := time.Now()
+	ret := t.Driver.WaitForFences(device, fenceCount, pFences, waitAll, timeout)
+	t.record("WaitForFences", start, &ret, nil, "device", device, "fenceCount", fenceCount, "waitAll", waitAll, "timeout", timeout)
+	return ret
+}
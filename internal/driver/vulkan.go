@@ -0,0 +1,306 @@
+package driver
+
+import (
+	"unsafe"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Vulkan is the Driver backed by vulkan-go.
+type Vulkan struct{}
+
+func (Vulkan) AcquireNextImage(device vk.Device, swapchain vk.Swapchain, timeout uint64, semaphore vk.Semaphore, fence vk.Fence, pImageIndex *uint32) vk.Result {
+	return vk.AcquireNextImage(device, swapchain, timeout, semaphore, fence, pImageIndex)
+}
+
+func (Vulkan) AllocateCommandBuffers(device vk.Device, pAllocateInfo *vk.CommandBufferAllocateInfo, pCommandBuffers []vk.CommandBuffer) vk.Result {
+	return vk.AllocateCommandBuffers(device, pAllocateInfo, pCommandBuffers)
+}
+
+func (Vulkan) AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo, pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result {
+	return vk.AllocateMemory(device, pAllocateInfo, pAllocator, pMemory)
+}
+
+func (Vulkan) BeginCommandBuffer(commandBuffer vk.CommandBuffer, pBeginInfo *vk.CommandBufferBeginInfo) vk.Result {
+	return vk.BeginCommandBuffer(commandBuffer, pBeginInfo)
+}
+
+func (Vulkan) BindBufferMemory(device vk.Device, buffer vk.Buffer, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result {
+	return vk.BindBufferMemory(device, buffer, memory, memoryOffset)
+}
+
+func (Vulkan) BindImageMemory(device vk.Device, image vk.Image, memory vk.DeviceMemory, memoryOffset vk.DeviceSize) vk.Result {
+	return vk.BindImageMemory(device, image, memory, memoryOffset)
+}
+
+func (Vulkan) CmdBeginDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	cmdBeginDebugUtilsLabel(commandBuffer, pLabelInfo)
+}
+
+func (Vulkan) CmdBeginRenderPass(commandBuffer vk.CommandBuffer, pRenderPassBegin *vk.RenderPassBeginInfo, contents vk.SubpassContents) {
+	vk.CmdBeginRenderPass(commandBuffer, pRenderPassBegin, contents)
+}
+
+func (Vulkan) CmdEndDebugUtilsLabel(commandBuffer vk.CommandBuffer) {
+	cmdEndDebugUtilsLabel(commandBuffer)
+}
+
+func (Vulkan) CmdExecuteCommands(commandBuffer vk.CommandBuffer, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer) {
+	vk.CmdExecuteCommands(commandBuffer, commandBufferCount, pCommandBuffers)
+}
+
+func (Vulkan) CmdInsertDebugUtilsLabel(commandBuffer vk.CommandBuffer, pLabelInfo *vk.DebugUtilsLabel) {
+	cmdInsertDebugUtilsLabel(commandBuffer, pLabelInfo)
+}
+
+func (Vulkan) CmdPipelineBarrier(commandBuffer vk.CommandBuffer, srcStageMask vk.PipelineStageFlags, dstStageMask vk.PipelineStageFlags, dependencyFlags vk.DependencyFlags, memoryBarrierCount uint32, pMemoryBarriers []vk.MemoryBarrier, bufferMemoryBarrierCount uint32, pBufferMemoryBarriers []vk.BufferMemoryBarrier, imageMemoryBarrierCount uint32, pImageMemoryBarriers []vk.ImageMemoryBarrier) {
+	vk.CmdPipelineBarrier(commandBuffer, srcStageMask, dstStageMask, dependencyFlags, memoryBarrierCount, pMemoryBarriers, bufferMemoryBarrierCount, pBufferMemoryBarriers, imageMemoryBarrierCount, pImageMemoryBarriers)
+}
+
+func (Vulkan) CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo, pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result {
+	return vk.CreateBuffer(device, pCreateInfo, pAllocator, pBuffer)
+}
+
+func (Vulkan) CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo, pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result {
+	return vk.CreateCommandPool(device, pCreateInfo, pAllocator, pCommandPool)
+}
+
+func (Vulkan) CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo, pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result {
+	return vk.CreateDebugReportCallback(instance, pCreateInfo, pAllocator, pCallback)
+}
+
+func (Vulkan) CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo, pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result {
+	return vk.CreateDevice(physicalDevice, pCreateInfo, pAllocator, pDevice)
+}
+
+func (Vulkan) CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo, pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result {
+	return vk.CreateFence(device, pCreateInfo, pAllocator, pFence)
+}
+
+func (Vulkan) CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo, pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result {
+	return vk.CreateFramebuffer(device, pCreateInfo, pAllocator, pFramebuffer)
+}
+
+func (Vulkan) CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo, pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result {
+	return vk.CreateImage(device, pCreateInfo, pAllocator, pImage)
+}
+
+func (Vulkan) CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo, pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result {
+	return vk.CreateImageView(device, pCreateInfo, pAllocator, pView)
+}
+
+func (Vulkan) CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks, pInstance *vk.Instance) vk.Result {
+	return vk.CreateInstance(pCreateInfo, pAllocator, pInstance)
+}
+
+func (Vulkan) CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo, pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result {
+	return vk.CreateRenderPass(device, pCreateInfo, pAllocator, pRenderPass)
+}
+
+func (Vulkan) CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo, pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result {
+	return vk.CreateSemaphore(device, pCreateInfo, pAllocator, pSemaphore)
+}
+
+func (Vulkan) CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo, pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result {
+	return vk.CreateShaderModule(device, pCreateInfo, pAllocator, pShaderModule)
+}
+
+func (Vulkan) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo, pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+	return vk.CreateSwapchain(device, pCreateInfo, pAllocator, pSwapchain)
+}
+
+func (Vulkan) DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyBuffer(device, buffer, pAllocator)
+}
+
+func (Vulkan) DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyCommandPool(device, commandPool, pAllocator)
+}
+
+func (Vulkan) DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyDebugReportCallback(instance, callback, pAllocator)
+}
+
+func (Vulkan) DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyDevice(device, pAllocator)
+}
+
+func (Vulkan) DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyFence(device, fence, pAllocator)
+}
+
+func (Vulkan) DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyFramebuffer(device, framebuffer, pAllocator)
+}
+
+func (Vulkan) DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyImage(device, image, pAllocator)
+}
+
+func (Vulkan) DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyImageView(device, imageView, pAllocator)
+}
+
+func (Vulkan) DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyInstance(instance, pAllocator)
+}
+
+func (Vulkan) DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyRenderPass(device, renderPass, pAllocator)
+}
+
+func (Vulkan) DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroySemaphore(device, semaphore, pAllocator)
+}
+
+func (Vulkan) DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroyShaderModule(device, shaderModule, pAllocator)
+}
+
+func (Vulkan) DestroySurface(instance vk.Instance, surface vk.Surface, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroySurface(instance, surface, pAllocator)
+}
+
+func (Vulkan) DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks) {
+	vk.DestroySwapchain(device, swapchain, pAllocator)
+}
+
+func (Vulkan) DeviceWaitIdle(device vk.Device) vk.Result {
+	return vk.DeviceWaitIdle(device)
+}
+
+func (Vulkan) EndCommandBuffer(commandBuffer vk.CommandBuffer) vk.Result {
+	return vk.EndCommandBuffer(commandBuffer)
+}
+
+func (Vulkan) EnumerateDeviceExtensionProperties(physicalDevice vk.PhysicalDevice, pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result {
+	return vk.EnumerateDeviceExtensionProperties(physicalDevice, pLayerName, pPropertyCount, pProperties)
+}
+
+func (Vulkan) EnumerateInstanceExtensionProperties(pLayerName string, pPropertyCount *uint32, pProperties []vk.ExtensionProperties) vk.Result {
+	return vk.EnumerateInstanceExtensionProperties(pLayerName, pPropertyCount, pProperties)
+}
+
+func (Vulkan) EnumerateInstanceVersion(pApiVersion *uint32) vk.Result {
+	return enumerateInstanceVersion(pApiVersion)
+}
+
+func (Vulkan) EnumerateInstanceLayerProperties(pPropertyCount *uint32, pProperties []vk.LayerProperties) vk.Result {
+	return vk.EnumerateInstanceLayerProperties(pPropertyCount, pProperties)
+}
+
+func (Vulkan) EnumeratePhysicalDevices(instance vk.Instance, pPhysicalDeviceCount *uint32, pPhysicalDevices []vk.PhysicalDevice) vk.Result {
+	return vk.EnumeratePhysicalDevices(instance, pPhysicalDeviceCount, pPhysicalDevices)
+}
+
+func (Vulkan) FreeCommandBuffers(device vk.Device, commandPool vk.CommandPool, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer) {
+	vk.FreeCommandBuffers(device, commandPool, commandBufferCount, pCommandBuffers)
+}
+
+func (Vulkan) FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks) {
+	vk.FreeMemory(device, memory, pAllocator)
+}
+
+func (Vulkan) GetBufferMemoryRequirements(device vk.Device, buffer vk.Buffer, pMemoryRequirements *vk.MemoryRequirements) {
+	vk.GetBufferMemoryRequirements(device, buffer, pMemoryRequirements)
+}
+
+func (Vulkan) GetDeviceQueue(device vk.Device, queueFamilyIndex uint32, queueIndex uint32, pQueue *vk.Queue) {
+	vk.GetDeviceQueue(device, queueFamilyIndex, queueIndex, pQueue)
+}
+
+func (Vulkan) GetFenceStatus(device vk.Device, fence vk.Fence) vk.Result {
+	return vk.GetFenceStatus(device, fence)
+}
+
+func (Vulkan) GetImageMemoryRequirements(device vk.Device, image vk.Image, pMemoryRequirements *vk.MemoryRequirements) {
+	vk.GetImageMemoryRequirements(device, image, pMemoryRequirements)
+}
+
+func (Vulkan) GetPhysicalDeviceFormatProperties(physicalDevice vk.PhysicalDevice, format vk.Format, pFormatProperties *vk.FormatProperties) {
+	vk.GetPhysicalDeviceFormatProperties(physicalDevice, format, pFormatProperties)
+}
+
+func (Vulkan) GetPhysicalDeviceMemoryProperties(physicalDevice vk.PhysicalDevice, pMemoryProperties *vk.PhysicalDeviceMemoryProperties) {
+	vk.GetPhysicalDeviceMemoryProperties(physicalDevice, pMemoryProperties)
+}
+
+func (Vulkan) GetPhysicalDeviceProperties(physicalDevice vk.PhysicalDevice, pProperties *vk.PhysicalDeviceProperties) {
+	vk.GetPhysicalDeviceProperties(physicalDevice, pProperties)
+}
+
+func (Vulkan) GetPhysicalDeviceQueueFamilyProperties(physicalDevice vk.PhysicalDevice, pQueueFamilyPropertyCount *uint32, pQueueFamilyProperties []vk.QueueFamilyProperties) {
+	vk.GetPhysicalDeviceQueueFamilyProperties(physicalDevice, pQueueFamilyPropertyCount, pQueueFamilyProperties)
+}
+
+func (Vulkan) GetPhysicalDeviceSurfaceCapabilities(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceCapabilities *vk.SurfaceCapabilities) vk.Result {
+	return vk.GetPhysicalDeviceSurfaceCapabilities(physicalDevice, surface, pSurfaceCapabilities)
+}
+
+func (Vulkan) GetPhysicalDeviceSurfaceFormats(physicalDevice vk.PhysicalDevice, surface vk.Surface, pSurfaceFormatCount *uint32, pSurfaceFormats []vk.SurfaceFormat) vk.Result {
+	return vk.GetPhysicalDeviceSurfaceFormats(physicalDevice, surface, pSurfaceFormatCount, pSurfaceFormats)
+}
+
+func (Vulkan) GetPhysicalDeviceSurfaceSupport(physicalDevice vk.PhysicalDevice, queueFamilyIndex uint32, surface vk.Surface, pSupported *vk.Bool32) vk.Result {
+	return vk.GetPhysicalDeviceSurfaceSupport(physicalDevice, queueFamilyIndex, surface, pSupported)
+}
+
+func (Vulkan) GetSwapchainImages(device vk.Device, swapchain vk.Swapchain, pSwapchainImageCount *uint32, pSwapchainImages []vk.Image) vk.Result {
+	return vk.GetSwapchainImages(device, swapchain, pSwapchainImageCount, pSwapchainImages)
+}
+
+func (Vulkan) InitInstance(instance vk.Instance) error {
+	if err := vk.InitInstance(instance); err != nil {
+		return err
+	}
+	loadInstanceProcs(instance)
+	return nil
+}
+
+func (Vulkan) MapMemory(device vk.Device, memory vk.DeviceMemory, offset vk.DeviceSize, size vk.DeviceSize, flags vk.MemoryMapFlags, ppData *unsafe.Pointer) vk.Result {
+	return vk.MapMemory(device, memory, offset, size, flags, ppData)
+}
+
+func (Vulkan) QueueBeginDebugUtilsLabel(queue vk.Queue, pLabelInfo *vk.DebugUtilsLabel) {
+	queueBeginDebugUtilsLabel(queue, pLabelInfo)
+}
+
+func (Vulkan) QueueEndDebugUtilsLabel(queue vk.Queue) {
+	queueEndDebugUtilsLabel(queue)
+}
+
+func (Vulkan) QueuePresent(queue vk.Queue, pPresentInfo *vk.PresentInfo) vk.Result {
+	return vk.QueuePresent(queue, pPresentInfo)
+}
+
+func (Vulkan) QueueSubmit(queue vk.Queue, submitCount uint32, pSubmits []vk.SubmitInfo, fence vk.Fence) vk.Result {
+	return vk.QueueSubmit(queue, submitCount, pSubmits, fence)
+}
+
+func (Vulkan) QueueWaitIdle(queue vk.Queue) vk.Result {
+	return vk.QueueWaitIdle(queue)
+}
+
+func (Vulkan) ResetCommandBuffer(commandBuffer vk.CommandBuffer, flags vk.CommandBufferResetFlags) vk.Result {
+	return vk.ResetCommandBuffer(commandBuffer, flags)
+}
+
+func (Vulkan) ResetCommandPool(device vk.Device, commandPool vk.CommandPool, flags vk.CommandPoolResetFlags) vk.Result {
+	return vk.ResetCommandPool(device, commandPool, flags)
+}
+
+func (Vulkan) ResetFences(device vk.Device, fenceCount uint32, pFences []vk.Fence) vk.Result {
+	return vk.ResetFences(device, fenceCount, pFences)
+}
+
+func (Vulkan) SetDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result {
+	return setDebugUtilsObjectName(device, pNameInfo)
+}
+
+func (Vulkan) UnmapMemory(device vk.Device, memory vk.DeviceMemory) {
+	vk.UnmapMemory(device, memory)
+}
+
+func (Vulkan) WaitForFences(device vk.Device, fenceCount uint32, pFences []vk.Fence, waitAll vk.Bool32, timeout uint64) vk.Result {
+	return vk.WaitForFences(device, fenceCount, pFences, waitAll, timeout)
+}
@@ -0,0 +1,364 @@
+package asche
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Leak is a Vulkan object created through asche that was not destroyed, see ApplicationLeakTracker.
+type Leak struct {
+	// Type is the object type, e.g. "Buffer" or "ShaderModule".
+	Type string
+	// Handle is the object handle.
+	Handle uint64
+	// Name is the debug name of the object, set by SetObjectName if VK_EXT_debug_utils is enabled.
+	Name string
+	// Stack is the call stack that created the object.
+	Stack string
+}
+
+func (l Leak) String() string {
+	name := ""
+	if l.Name != "" {
+		name = fmt.Sprintf(" %q", l.Name)
+	}
+	return fmt.Sprintf("%s %#x%s created at\n%s", l.Type, l.Handle, name, l.Stack)
+}
+
+type leakKey struct {
+	typ    string
+	handle uint64
+}
+
+// leakObjectTypes maps the object types of VK_EXT_debug_utils to the types of the tracked handles.
+var leakObjectTypes = map[vk.ObjectType]string{
+	vk.ObjectTypeInstance:            "Instance",
+	vk.ObjectTypeDevice:              "Device",
+	vk.ObjectTypeCommandBuffer:       "CommandBuffer",
+	vk.ObjectTypeSemaphore:           "Semaphore",
+	vk.ObjectTypeFence:               "Fence",
+	vk.ObjectTypeDeviceMemory:        "DeviceMemory",
+	vk.ObjectTypeBuffer:              "Buffer",
+	vk.ObjectTypeImage:               "Image",
+	vk.ObjectTypeImageView:           "ImageView",
+	vk.ObjectTypeShaderModule:        "ShaderModule",
+	vk.ObjectTypeRenderPass:          "RenderPass",
+	vk.ObjectTypeFramebuffer:         "Framebuffer",
+	vk.ObjectTypeCommandPool:         "CommandPool",
+	vk.ObjectTypeSwapchain:           "Swapchain",
+	vk.ObjectTypeDebugReportCallback: "DebugReportCallback",
+}
+
+type leakObject struct {
+	seq    uint64
+	name   string
+	parent leakKey
+	stack  []uintptr
+}
+
+// leakTracker is a driver that records the objects created through it until they are destroyed,
+// command buffers are released along with their pool.
+type leakTracker struct {
+	driver.Driver
+
+	mu   sync.Mutex
+	seq  uint64
+	live map[leakKey]*leakObject
+}
+
+// newLeakTracker records the objects created by the Vulkan calls made through it.
+func newLeakTracker(d driver.Driver) *leakTracker {
+	return &leakTracker{
+		Driver: d,
+		live:   make(map[leakKey]*leakObject),
+	}
+}
+
+// handleKey gets the key of a handle, it's false for null handles.
+func handleKey(object interface{}) (leakKey, bool) {
+	rv := reflect.ValueOf(object)
+	var handle uint64
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.UnsafePointer:
+		handle = uint64(rv.Pointer())
+	case reflect.Uint, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		handle = rv.Uint()
+	}
+	return leakKey{
+		typ:    rv.Type().Name(),
+		handle: handle,
+	}, handle != 0
+}
+
+func (t *leakTracker) track(ret vk.Result, object interface{}, parent interface{}) {
+	if ret != vk.Success {
+		return
+	}
+	key, ok := handleKey(object)
+	if !ok {
+		return
+	}
+	obj := &leakObject{
+		stack: make([]uintptr, 32),
+	}
+	// skip runtime.Callers, track and the driver method
+	obj.stack = obj.stack[:runtime.Callers(3, obj.stack)]
+	if parent != nil {
+		obj.parent, _ = handleKey(parent)
+	}
+	t.mu.Lock()
+	t.seq++
+	obj.seq = t.seq
+	t.live[key] = obj
+	t.mu.Unlock()
+}
+
+func (t *leakTracker) untrack(object interface{}) {
+	key, ok := handleKey(object)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	delete(t.live, key)
+	t.mu.Unlock()
+}
+
+// leaks gets the objects still alive in the order of creation.
+func (t *leakTracker) leaks() []Leak {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]leakKey, 0, len(t.live))
+	for key := range t.live {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return t.live[keys[i]].seq < t.live[keys[j]].seq
+	})
+	leaks := make([]Leak, 0, len(keys))
+	for _, key := range keys {
+		obj := t.live[key]
+		leaks = append(leaks, Leak{
+			Type:   key.typ,
+			Handle: key.handle,
+			Name:   obj.name,
+			Stack:  formatStack(obj.stack),
+		})
+	}
+	return leaks
+}
+
+func formatStack(pcs []uintptr) string {
+	var buf strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			fmt.Fprintf(&buf, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func (t *leakTracker) AllocateCommandBuffers(device vk.Device, pAllocateInfo *vk.CommandBufferAllocateInfo, pCommandBuffers []vk.CommandBuffer) vk.Result {
+	ret := t.Driver.AllocateCommandBuffers(device, pAllocateInfo, pCommandBuffers)
+	for _, cmd := range pCommandBuffers[:pAllocateInfo.CommandBufferCount] {
+		t.track(ret, cmd, pAllocateInfo.CommandPool)
+	}
+	return ret
+}
+
+func (t *leakTracker) AllocateMemory(device vk.Device, pAllocateInfo *vk.MemoryAllocateInfo, pAllocator *vk.AllocationCallbacks, pMemory *vk.DeviceMemory) vk.Result {
+	ret := t.Driver.AllocateMemory(device, pAllocateInfo, pAllocator, pMemory)
+	t.track(ret, *pMemory, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateBuffer(device vk.Device, pCreateInfo *vk.BufferCreateInfo, pAllocator *vk.AllocationCallbacks, pBuffer *vk.Buffer) vk.Result {
+	ret := t.Driver.CreateBuffer(device, pCreateInfo, pAllocator, pBuffer)
+	t.track(ret, *pBuffer, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateCommandPool(device vk.Device, pCreateInfo *vk.CommandPoolCreateInfo, pAllocator *vk.AllocationCallbacks, pCommandPool *vk.CommandPool) vk.Result {
+	ret := t.Driver.CreateCommandPool(device, pCreateInfo, pAllocator, pCommandPool)
+	t.track(ret, *pCommandPool, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateDebugReportCallback(instance vk.Instance, pCreateInfo *vk.DebugReportCallbackCreateInfo, pAllocator *vk.AllocationCallbacks, pCallback *vk.DebugReportCallback) vk.Result {
+	ret := t.Driver.CreateDebugReportCallback(instance, pCreateInfo, pAllocator, pCallback)
+	t.track(ret, *pCallback, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateDevice(physicalDevice vk.PhysicalDevice, pCreateInfo *vk.DeviceCreateInfo, pAllocator *vk.AllocationCallbacks, pDevice *vk.Device) vk.Result {
+	ret := t.Driver.CreateDevice(physicalDevice, pCreateInfo, pAllocator, pDevice)
+	t.track(ret, *pDevice, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateFence(device vk.Device, pCreateInfo *vk.FenceCreateInfo, pAllocator *vk.AllocationCallbacks, pFence *vk.Fence) vk.Result {
+	ret := t.Driver.CreateFence(device, pCreateInfo, pAllocator, pFence)
+	t.track(ret, *pFence, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateFramebuffer(device vk.Device, pCreateInfo *vk.FramebufferCreateInfo, pAllocator *vk.AllocationCallbacks, pFramebuffer *vk.Framebuffer) vk.Result {
+	ret := t.Driver.CreateFramebuffer(device, pCreateInfo, pAllocator, pFramebuffer)
+	t.track(ret, *pFramebuffer, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateImage(device vk.Device, pCreateInfo *vk.ImageCreateInfo, pAllocator *vk.AllocationCallbacks, pImage *vk.Image) vk.Result {
+	ret := t.Driver.CreateImage(device, pCreateInfo, pAllocator, pImage)
+	t.track(ret, *pImage, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateImageView(device vk.Device, pCreateInfo *vk.ImageViewCreateInfo, pAllocator *vk.AllocationCallbacks, pView *vk.ImageView) vk.Result {
+	ret := t.Driver.CreateImageView(device, pCreateInfo, pAllocator, pView)
+	t.track(ret, *pView, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateInstance(pCreateInfo *vk.InstanceCreateInfo, pAllocator *vk.AllocationCallbacks, pInstance *vk.Instance) vk.Result {
+	ret := t.Driver.CreateInstance(pCreateInfo, pAllocator, pInstance)
+	t.track(ret, *pInstance, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateRenderPass(device vk.Device, pCreateInfo *vk.RenderPassCreateInfo, pAllocator *vk.AllocationCallbacks, pRenderPass *vk.RenderPass) vk.Result {
+	ret := t.Driver.CreateRenderPass(device, pCreateInfo, pAllocator, pRenderPass)
+	t.track(ret, *pRenderPass, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateSemaphore(device vk.Device, pCreateInfo *vk.SemaphoreCreateInfo, pAllocator *vk.AllocationCallbacks, pSemaphore *vk.Semaphore) vk.Result {
+	ret := t.Driver.CreateSemaphore(device, pCreateInfo, pAllocator, pSemaphore)
+	t.track(ret, *pSemaphore, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateShaderModule(device vk.Device, pCreateInfo *vk.ShaderModuleCreateInfo, pAllocator *vk.AllocationCallbacks, pShaderModule *vk.ShaderModule) vk.Result {
+	ret := t.Driver.CreateShaderModule(device, pCreateInfo, pAllocator, pShaderModule)
+	t.track(ret, *pShaderModule, nil)
+	return ret
+}
+
+func (t *leakTracker) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo, pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+	ret := t.Driver.CreateSwapchain(device, pCreateInfo, pAllocator, pSwapchain)
+	t.track(ret, *pSwapchain, nil)
+	return ret
+}
+
+func (t *leakTracker) DestroyBuffer(device vk.Device, buffer vk.Buffer, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(buffer)
+	t.Driver.DestroyBuffer(device, buffer, pAllocator)
+}
+
+// DestroyCommandPool releases the command buffers of the pool as well.
+func (t *leakTracker) DestroyCommandPool(device vk.Device, commandPool vk.CommandPool, pAllocator *vk.AllocationCallbacks) {
+	if pool, ok := handleKey(commandPool); ok {
+		t.mu.Lock()
+		for key, obj := range t.live {
+			if obj.parent == pool {
+				delete(t.live, key)
+			}
+		}
+		delete(t.live, pool)
+		t.mu.Unlock()
+	}
+	t.Driver.DestroyCommandPool(device, commandPool, pAllocator)
+}
+
+func (t *leakTracker) DestroyDebugReportCallback(instance vk.Instance, callback vk.DebugReportCallback, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(callback)
+	t.Driver.DestroyDebugReportCallback(instance, callback, pAllocator)
+}
+
+func (t *leakTracker) DestroyDevice(device vk.Device, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(device)
+	t.Driver.DestroyDevice(device, pAllocator)
+}
+
+func (t *leakTracker) DestroyFence(device vk.Device, fence vk.Fence, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(fence)
+	t.Driver.DestroyFence(device, fence, pAllocator)
+}
+
+func (t *leakTracker) DestroyFramebuffer(device vk.Device, framebuffer vk.Framebuffer, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(framebuffer)
+	t.Driver.DestroyFramebuffer(device, framebuffer, pAllocator)
+}
+
+func (t *leakTracker) DestroyImage(device vk.Device, image vk.Image, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(image)
+	t.Driver.DestroyImage(device, image, pAllocator)
+}
+
+func (t *leakTracker) DestroyImageView(device vk.Device, imageView vk.ImageView, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(imageView)
+	t.Driver.DestroyImageView(device, imageView, pAllocator)
+}
+
+func (t *leakTracker) DestroyInstance(instance vk.Instance, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(instance)
+	t.Driver.DestroyInstance(instance, pAllocator)
+}
+
+func (t *leakTracker) DestroyRenderPass(device vk.Device, renderPass vk.RenderPass, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(renderPass)
+	t.Driver.DestroyRenderPass(device, renderPass, pAllocator)
+}
+
+func (t *leakTracker) DestroySemaphore(device vk.Device, semaphore vk.Semaphore, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(semaphore)
+	t.Driver.DestroySemaphore(device, semaphore, pAllocator)
+}
+
+func (t *leakTracker) DestroyShaderModule(device vk.Device, shaderModule vk.ShaderModule, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(shaderModule)
+	t.Driver.DestroyShaderModule(device, shaderModule, pAllocator)
+}
+
+func (t *leakTracker) DestroySwapchain(device vk.Device, swapchain vk.Swapchain, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(swapchain)
+	t.Driver.DestroySwapchain(device, swapchain, pAllocator)
+}
+
+func (t *leakTracker) FreeCommandBuffers(device vk.Device, commandPool vk.CommandPool, commandBufferCount uint32, pCommandBuffers []vk.CommandBuffer) {
+	for _, cmd := range pCommandBuffers[:commandBufferCount] {
+		t.untrack(cmd)
+	}
+	t.Driver.FreeCommandBuffers(device, commandPool, commandBufferCount, pCommandBuffers)
+}
+
+func (t *leakTracker) FreeMemory(device vk.Device, memory vk.DeviceMemory, pAllocator *vk.AllocationCallbacks) {
+	t.untrack(memory)
+	t.Driver.FreeMemory(device, memory, pAllocator)
+}
+
+// SetDebugUtilsObjectName keeps the name to report along with the object.
+func (t *leakTracker) SetDebugUtilsObjectName(device vk.Device, pNameInfo *vk.DebugUtilsObjectNameInfo) vk.Result {
+	ret := t.Driver.SetDebugUtilsObjectName(device, pNameInfo)
+	typ, ok := leakObjectTypes[pNameInfo.ObjectType]
+	if !ok {
+		return ret
+	}
+	t.mu.Lock()
+	if obj, ok := t.live[leakKey{typ: typ, handle: pNameInfo.ObjectHandle}]; ok {
+		obj.name = strings.TrimSuffix(pNameInfo.PObjectName, end)
+	}
+	t.mu.Unlock()
+	return ret
+}
@@ -0,0 +1,76 @@
+package asche
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestLeakTrackerObjectName(t *testing.T) {
+	tests := []struct {
+		name       string
+		objectType vk.ObjectType
+		other      bool
+		want       map[string]string
+	}{
+		{
+			name:       "buffer",
+			objectType: vk.ObjectTypeBuffer,
+			want:       map[string]string{"Buffer": "named", "Image": ""},
+		},
+		{
+			name:       "image with the same handle",
+			objectType: vk.ObjectTypeImage,
+			want:       map[string]string{"Buffer": "", "Image": "named"},
+		},
+		{
+			name:       "untracked type",
+			objectType: vk.ObjectTypeSampler,
+			want:       map[string]string{"Buffer": "", "Image": ""},
+		},
+		{
+			name:       "other handle",
+			objectType: vk.ObjectTypeBuffer,
+			other:      true,
+			want:       map[string]string{"Buffer": "", "Image": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newLeakTracker(fake.New())
+			var buffer, other vk.Buffer
+			for _, b := range []*vk.Buffer{&buffer, &other} {
+				ret := tracker.CreateBuffer(nil, &vk.BufferCreateInfo{Size: 16}, nil, b)
+				if ret != vk.Success {
+					t.Fatal(NewError(ret))
+				}
+			}
+			tracker.DestroyBuffer(nil, other, nil)
+			// the handles of different types may be equal
+			tracker.track(vk.Success, vk.Image(unsafe.Pointer(buffer)), nil)
+
+			handle := buffer
+			if tt.other {
+				handle = other
+			}
+			_, objectHandle, _ := objectHandle(handle)
+			tracker.SetDebugUtilsObjectName(nil, &vk.DebugUtilsObjectNameInfo{
+				SType:        vk.StructureTypeDebugUtilsObjectNameInfo,
+				ObjectType:   tt.objectType,
+				ObjectHandle: objectHandle,
+				PObjectName:  "named\x00",
+			})
+			leaks := tracker.leaks()
+			if len(leaks) != len(tt.want) {
+				t.Fatalf("got %d leaks, want %d", len(leaks), len(tt.want))
+			}
+			for _, leak := range leaks {
+				if want := tt.want[leak.Type]; leak.Name != want {
+					t.Errorf("%s named %q, want %q", leak.Type, leak.Name, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package asche
+
+import (
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// ClampSampleCount gets the highest sample count supported by all the given
+// sample count masks that doesn't exceed the requested one.
+func ClampSampleCount(requested vk.SampleCountFlagBits, supported ...vk.SampleCountFlags) vk.SampleCountFlagBits {
+lookup:
+	for count := vk.SampleCount64Bit; count > vk.SampleCount1Bit; count >>= 1 {
+		if count > requested {
+			continue
+		}
+		for _, mask := range supported {
+			if mask&vk.SampleCountFlags(count) == 0 {
+				continue lookup
+			}
+		}
+		return count
+	}
+	return vk.SampleCount1Bit
+}
+
+// prepareColorTarget (re)creates the transient multisampled color image the default
+// render pass draws into before resolving to the swapchain image.
+func (c *context) prepareColorTarget() {
+	if c.colorImage != nil {
+		c.colorImage.Destroy()
+		c.colorImage = nil
+	}
+	c.colorImage = c.createAttachment(c.swapchainDimensions.Format, c.sampleCount,
+		vk.ImageUsageColorAttachmentBit|vk.ImageUsageTransientAttachmentBit,
+		vk.ImageAspectFlags(vk.ImageAspectColorBit),
+		vk.MemoryPropertyLazilyAllocatedBit, vk.MemoryPropertyDeviceLocalBit)
+	c.nameObject(c.colorImage, "multisample color")
+}
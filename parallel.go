@@ -0,0 +1,188 @@
+package asche
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// workerPool is a command pool of a frame slot used by a single worker,
+// its secondary command buffers are recycled when the slot is reused.
+// The lock guards the pool against workers sharing an index by mistake.
+type workerPool struct {
+	mu      sync.Mutex
+	pool    vk.CommandPool
+	buffers []vk.CommandBuffer
+	used    int
+}
+
+// workerPools is the registry of command pools per frame slot and worker.
+type workerPools struct {
+	mu    sync.Mutex
+	slots [][]*workerPool
+}
+
+func (r *workerPools) get(c *context, slot, worker int) *workerPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.slots) <= slot {
+		r.slots = append(r.slots, nil)
+	}
+	for len(r.slots[slot]) <= worker {
+		r.slots[slot] = append(r.slots[slot], nil)
+	}
+	if wp := r.slots[slot][worker]; wp != nil {
+		return wp
+	}
+	wp := &workerPool{}
+	ret := c.vkd.CreateCommandPool(c.device, &vk.CommandPoolCreateInfo{
+		SType:            vk.StructureTypeCommandPoolCreateInfo,
+		Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateTransientBit),
+		QueueFamilyIndex: c.platform.GraphicsQueueFamilyIndex(),
+	}, nil, &wp.pool)
+	orPanic(NewError(ret))
+	c.nameObject(wp.pool, "worker command pool %d/%d", slot, worker)
+	r.slots[slot][worker] = wp
+	return wp
+}
+
+// reset recycles the command buffers of the frame slot, the slot must not be in use by the device.
+func (r *workerPools) reset(device vk.Device, slot int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if slot >= len(r.slots) {
+		return
+	}
+	for _, wp := range r.slots[slot] {
+		if wp == nil {
+			continue
+		}
+		wp.mu.Lock()
+		if wp.used > 0 {
+			deviceDriver(device).ResetCommandPool(device, wp.pool, 0)
+			wp.used = 0
+		}
+		wp.mu.Unlock()
+	}
+}
+
+func (r *workerPools) destroy(device vk.Device) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, pools := range r.slots {
+		for _, wp := range pools {
+			if wp != nil {
+				deviceDriver(device).DestroyCommandPool(device, wp.pool, nil)
+			}
+		}
+	}
+	r.slots = nil
+}
+
+func (c *context) SecondaryCommandBuffer(frame *Frame, worker int) (cmd vk.CommandBuffer, err error) {
+	defer c.checkValidation(&err)
+	defer checkErr(&err)
+	wp := c.workerPools.get(c, frame.slot, worker)
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if wp.used == len(wp.buffers) {
+		buffers := make([]vk.CommandBuffer, 1)
+		ret := c.vkd.AllocateCommandBuffers(c.device, &vk.CommandBufferAllocateInfo{
+			SType:              vk.StructureTypeCommandBufferAllocateInfo,
+			CommandPool:        wp.pool,
+			Level:              vk.CommandBufferLevelSecondary,
+			CommandBufferCount: 1,
+		}, buffers)
+		orPanic(NewError(ret))
+		wp.buffers = append(wp.buffers, buffers[0])
+	}
+	cmd = wp.buffers[wp.used]
+	wp.used++
+
+	flags := vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit)
+	inheritance := vk.CommandBufferInheritanceInfo{
+		SType: vk.StructureTypeCommandBufferInheritanceInfo,
+	}
+	if c.renderPass != vk.NullRenderPass {
+		flags |= vk.CommandBufferUsageFlags(vk.CommandBufferUsageRenderPassContinueBit)
+		inheritance.RenderPass = c.renderPass
+		inheritance.Framebuffer = frame.Resources.framebuffer
+	}
+	ret := c.vkd.BeginCommandBuffer(cmd, &vk.CommandBufferBeginInfo{
+		SType:            vk.StructureTypeCommandBufferBeginInfo,
+		Flags:            flags,
+		PInheritanceInfo: []vk.CommandBufferInheritanceInfo{inheritance},
+	})
+	orPanic(NewError(ret))
+	return cmd, nil
+}
+
+func (c *context) RecordParallel(frame *Frame, chunks ...func(cmd vk.CommandBuffer) error) (err error) {
+	defer c.checkValidation(&err)
+	cmds := make([]vk.CommandBuffer, len(chunks))
+	errs := make([]error, len(chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func(worker int, chunk func(cmd vk.CommandBuffer) error) {
+			defer wg.Done()
+			errs[worker] = c.recordWorker(frame, worker, chunk, &cmds[worker])
+		}(i, chunk)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if len(cmds) > 0 {
+		c.vkd.CmdExecuteCommands(frame.CommandBuffer, uint32(len(cmds)), cmds)
+	}
+	return nil
+}
+
+// recordWorker records a chunk of RecordParallel. It recovers any panic into the error
+// along with the stack, as the caller can't recover panics of the worker goroutine.
+func (c *context) recordWorker(frame *Frame, worker int, chunk func(cmd vk.CommandBuffer) error,
+	out *vk.CommandBuffer) (err error) {
+
+	defer func() {
+		if v := recover(); v != nil {
+			if e, ok := v.(error); ok {
+				err = fmt.Errorf("vulkan error: worker %d panicked: %w\n%s", worker, e, debug.Stack())
+			} else {
+				err = fmt.Errorf("vulkan error: worker %d panicked: %v\n%s", worker, v, debug.Stack())
+			}
+		}
+	}()
+	cmd, err := c.SecondaryCommandBuffer(frame, worker)
+	if err != nil {
+		return err
+	}
+	if err := chunk(cmd); err != nil {
+		c.vkd.EndCommandBuffer(cmd)
+		return err
+	}
+	if err := NewError(c.vkd.EndCommandBuffer(cmd)); err != nil {
+		return err
+	}
+	*out = cmd
+	return nil
+}
+
+func (c *context) BeginRenderPass(frame *Frame, contents vk.SubpassContents) {
+	c.vkd.CmdBeginRenderPass(frame.CommandBuffer, &vk.RenderPassBeginInfo{
+		SType:       vk.StructureTypeRenderPassBeginInfo,
+		RenderPass:  c.renderPass,
+		Framebuffer: frame.Resources.framebuffer,
+		RenderArea: vk.Rect2D{
+			Extent: vk.Extent2D{
+				Width:  c.swapchainDimensions.Width,
+				Height: c.swapchainDimensions.Height,
+			},
+		},
+		ClearValueCount: uint32(len(c.clearValues)),
+		PClearValues:    c.clearValues,
+	}, contents)
+}
@@ -0,0 +1,103 @@
+package asche
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestRecordParallel(t *testing.T) {
+	errChunk := errors.New("chunk failed")
+	ok := func(cmd vk.CommandBuffer) error { return nil }
+	tests := []struct {
+		name   string
+		chunks []func(cmd vk.CommandBuffer) error
+		// want are the substrings of the error, nil if it must succeed
+		want []string
+	}{
+		{
+			name:   "ok",
+			chunks: []func(cmd vk.CommandBuffer) error{ok, ok, ok},
+		},
+		{
+			name: "error",
+			chunks: []func(cmd vk.CommandBuffer) error{ok, func(cmd vk.CommandBuffer) error {
+				return errChunk
+			}},
+			want: []string{"chunk failed"},
+		},
+		{
+			name: "runtime error",
+			chunks: []func(cmd vk.CommandBuffer) error{ok, func(cmd vk.CommandBuffer) error {
+				var m map[string]int
+				m["x"] = 1
+				return nil
+			}},
+			want: []string{"worker 1 panicked", "assignment to entry in nil map", "parallel_test.go"},
+		},
+		{
+			name: "panic and error",
+			chunks: []func(cmd vk.CommandBuffer) error{
+				func(cmd vk.CommandBuffer) error { panic("boom") },
+				func(cmd vk.CommandBuffer) error { return errChunk },
+			},
+			want: []string{"worker 0 panicked: boom", "chunk failed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			newFakePlatform(t, app)
+			ctx := app.Context()
+
+			frame, _, err := ctx.BeginFrame()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var done int32
+			chunks := make([]func(cmd vk.CommandBuffer) error, len(tt.chunks))
+			for i, chunk := range tt.chunks {
+				chunk := chunk
+				chunks[i] = func(cmd vk.CommandBuffer) error {
+					defer atomic.AddInt32(&done, 1)
+					return chunk(cmd)
+				}
+			}
+			err = ctx.RecordParallel(frame, chunks...)
+			if n := atomic.LoadInt32(&done); int(n) != len(chunks) {
+				t.Errorf("returned after %d of %d chunks", n, len(chunks))
+			}
+			executed := countCalls(app.fd, "CmdExecuteCommands")
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if executed != 1 {
+					t.Errorf("secondary command buffers executed %d times, want 1", executed)
+				}
+			} else {
+				if err == nil {
+					t.Fatal("RecordParallel succeeded")
+				}
+				for _, want := range tt.want {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q doesn't contain %q", err, want)
+					}
+				}
+				if strings.Contains(err.Error(), "chunk failed") && !errors.Is(err, errChunk) {
+					t.Error("chunk error not matchable")
+				}
+				if executed != 0 {
+					t.Error("secondary command buffers executed despite the error")
+				}
+			}
+			if _, err := ctx.EndFrame(frame); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
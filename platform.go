@@ -5,6 +5,8 @@ import (
 	"log"
 	"unsafe"
 
+	"github.com/vulkan-go/asche/internal/driver"
+	"github.com/vulkan-go/asche/trace"
 	vk "github.com/vulkan-go/vulkan"
 )
 
@@ -20,49 +22,145 @@ type Platform interface {
 	// HasSeparatePresentQueue is true when PresentQueueFamilyIndex differs from GraphicsQueueFamilyIndex.
 	HasSeparatePresentQueue() bool
 	// GraphicsQueue gets the current Vulkan graphics queue.
+	// Use SyncGraphicsQueue to submit work to it from multiple goroutines.
 	GraphicsQueue() vk.Queue
 	// PresentQueue gets the current Vulkan present queue.
 	PresentQueue() vk.Queue
+	// SyncGraphicsQueue gets the graphics queue that serializes access across goroutines,
+	// the context submits through it too.
+	SyncGraphicsQueue() *Queue
+	// SyncPresentQueue gets the present queue that serializes access across goroutines,
+	// it's the same object as SyncGraphicsQueue unless HasSeparatePresentQueue.
+	SyncPresentQueue() *Queue
+	// DeviceWaitIdle waits for the device to become idle, holding all the queues meanwhile.
+	DeviceWaitIdle() error
 	// Instance gets the current Vulkan instance.
 	Instance() vk.Instance
 	// Device gets the current Vulkan device.
 	Device() vk.Device
+	// AllocationStats gets the host memory held by the driver per subsystem,
+	// it's nil unless enabled by ApplicationAllocationStats.
+	AllocationStats() *AllocationStats
+	// APIVersion gets the Vulkan version negotiated between the application, the loader and the device,
+	// extensions promoted to core in this version are not enabled explicitly.
+	APIVersion() vk.Version
 	// PhysicalDevice gets the current Vulkan physical device.
 	PhysicalDevice() vk.PhysicalDevice
 	// Surface gets the current Vulkan surface.
 	Surface() vk.Surface
+	// DetachSurface destroys the current surface along with the swapchain, keeping the device
+	// and the application state. The context stays suspended until a surface is attached.
+	DetachSurface()
+	// AttachSurface obtains a new surface via Application.VulkanSurface and re-creates the swapchain,
+	// the surface must be supported by the present queue family of the device.
+	AttachSurface() error
+	// RecoverDevice re-creates the logical device and the context after ErrDeviceLost,
+	// keeping the instance and the surface. The application is notified via ApplicationDeviceLost
+	// and initialized again with VulkanInit, so it can rebuild its resources.
+	RecoverDevice() error
 	// Destroy is the destructor for the Platform instance.
 	Destroy()
 }
 
 func NewPlatform(app Application) (pFace Platform, err error) {
-	// defer checkErr(&err)
 	p := &platform{
 		basePlatform: basePlatform{
-			context: &context{
-				// TODO: make configurable
-				// defines count of slots allocated in swapchain
-				frameLag: 3,
-			},
+			vkd:     vkd,
+			context: &context{},
 		},
+		app: app,
+	}
+	// destroy whatever is created already if init fails
+	var initialized bool
+	defer func() {
+		if !initialized {
+			p.Destroy()
+		}
+	}()
+	defer checkErr(&err)
+
+	if iface, ok := app.(ApplicationTrace); ok {
+		if w := iface.VulkanTrace(); w != nil {
+			p.trace = w
+			p.vkd = driver.Tracing{
+				Driver: p.vkd,
+				Trace:  w,
+			}
+		}
+	}
+	if _, ok := app.(ApplicationLeakTracker); ok {
+		p.leaks = newLeakTracker(p.vkd)
+		p.vkd = p.leaks
+	}
+	if iface, ok := app.(ApplicationAllocationStats); ok && iface.VulkanAllocationStats() {
+		p.allocator, err = newHostAllocator(p.vkd)
+		orPanic(err)
+		p.vkd = p.allocator
+	}
+
+	// Negotiate the API version of the instance
+	minVersion := makeVersion(1, 0)
+	if iface, ok := app.(ApplicationMinAPIVersion); ok {
+		minVersion = iface.VulkanMinAPIVersion()
+	}
+	instanceVersion := negotiateVersion(app.VulkanAPIVersion(), loaderVersion(p.vkd))
+	if err := checkMinVersion("the instance", instanceVersion, minVersion); err != nil {
+		return nil, err
 	}
-	p.context.platform = p
 
 	// Select instance extensions
-	requiredInstanceExtensions := safeStrings(app.VulkanInstanceExtensions())
-	actualInstanceExtensions, err := InstanceExtensions()
+	requiredInstanceExtensions := dropPromoted(safeStrings(app.VulkanInstanceExtensions()), instanceVersion)
+	actualInstanceExtensions, err := enumerateInstanceExtensions(p.vkd)
 	orPanic(err)
+	var validationCfg *ValidationConfig
+	if iface, ok := app.(ApplicationValidation); ok {
+		validationCfg = iface.VulkanValidation()
+	}
+	if validationCfg != nil {
+		// extensions of the layer are not reported along with the other ones
+		if layerExtensions, err := enumerateLayerExtensions(p.vkd, DefaultValidationLayer); err == nil {
+			actualInstanceExtensions = append(actualInstanceExtensions, layerExtensions...)
+		}
+		requiredInstanceExtensions = mergeExtensions(requiredInstanceExtensions, "VK_EXT_debug_report\x00")
+		if len(validationCfg.enables()) > 0 || len(validationCfg.Disable) > 0 {
+			requiredInstanceExtensions = mergeExtensions(requiredInstanceExtensions, "VK_EXT_validation_features\x00")
+		}
+	}
+	if wsiExtensionsRequired(app) {
+		requiredInstanceExtensions = mergeExtensions(requiredInstanceExtensions,
+			wsiInstanceExtensions(actualInstanceExtensions)...)
+	}
+	if app.VulkanDebug() || validationCfg != nil {
+		// name objects and label commands when available
+		debugUtils, _ := checkExisting(actualInstanceExtensions, []string{"VK_EXT_debug_utils\x00"})
+		requiredInstanceExtensions = mergeExtensions(requiredInstanceExtensions, debugUtils...)
+	}
+	requestedExtensions := append(append([]string(nil), requiredInstanceExtensions...),
+		safeStrings(app.VulkanDeviceExtensions())...)
+	requiredInstanceExtensions = mergeExtensions(requiredInstanceExtensions,
+		instanceDependencies(requestedExtensions, instanceVersion)...)
 	instanceExtensions, missing := checkExisting(actualInstanceExtensions, requiredInstanceExtensions)
 	if missing > 0 {
 		log.Println("vulkan warning: missing", missing, "required instance extensions during init")
 	}
+	instanceExtensions, err = resolveDependencies(instanceExtensions, actualInstanceExtensions, nil,
+		true, makeVersion(1, 0), instanceVersion)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("vulkan: enabling %d instance extensions", len(instanceExtensions))
+	p.debugUtils = hasString(instanceExtensions, "VK_EXT_debug_utils\x00")
 
 	// Select instance layers
-	var validationLayers []string
+	var validationLayers, requiredValidationLayers []string
 	if iface, ok := app.(ApplicationVulkanLayers); ok {
-		requiredValidationLayers := safeStrings(iface.VulkanLayers())
-		actualValidationLayers, err := ValidationLayers()
+		requiredValidationLayers = safeStrings(iface.VulkanLayers())
+	}
+	if validationCfg != nil {
+		requiredValidationLayers = mergeExtensions(requiredValidationLayers, safeString(DefaultValidationLayer))
+	}
+	if len(requiredValidationLayers) > 0 {
+		actualValidationLayers, err := enumerateLayers(p.vkd)
 		orPanic(err)
 		validationLayers, missing = checkExisting(actualValidationLayers, requiredValidationLayers)
 		if missing > 0 {
@@ -71,12 +169,19 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 	}
 
 	// Create instance
+	var features unsafe.Pointer
+	if validationCfg != nil {
+		if features = validationFeatures(validationCfg, instanceExtensions); features != nil {
+			defer freeValidationFeatures(features)
+		}
+	}
 	var instance vk.Instance
-	ret := vk.CreateInstance(&vk.InstanceCreateInfo{
+	ret := p.vkd.CreateInstance(&vk.InstanceCreateInfo{
 		SType: vk.StructureTypeInstanceCreateInfo,
+		PNext: features,
 		PApplicationInfo: &vk.ApplicationInfo{
 			SType:              vk.StructureTypeApplicationInfo,
-			ApiVersion:         uint32(app.VulkanAPIVersion()),
+			ApiVersion:         uint32(instanceVersion),
 			ApplicationVersion: uint32(app.VulkanAppVersion()),
 			PApplicationName:   safeString(app.VulkanAppName()),
 			PEngineName:        "vulkango.com\x00",
@@ -88,11 +193,22 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 	}, nil, &instance)
 	orPanic(NewError(ret))
 	p.instance = instance
-	vk.InitInstance(instance)
-
-	if app.VulkanDebug() {
+	p.vkd.InitInstance(instance)
+
+	if validationCfg != nil {
+		// Register a callback filtering the validation messages
+		p.validation = newValidation(validationCfg)
+		ret := p.vkd.CreateDebugReportCallback(instance, &vk.DebugReportCallbackCreateInfo{
+			SType: vk.StructureTypeDebugReportCallbackCreateInfo,
+			Flags: vk.DebugReportFlags(vk.DebugReportErrorBit | vk.DebugReportWarningBit |
+				vk.DebugReportPerformanceWarningBit),
+			PfnCallback: p.validation.callback,
+		}, nil, &p.debugCallback)
+		orPanic(NewError(ret))
+		log.Println("vulkan: validation enabled by application")
+	} else if app.VulkanDebug() {
 		// Register a debug callback
-		ret := vk.CreateDebugReportCallback(instance, &vk.DebugReportCallbackCreateInfo{
+		ret := p.vkd.CreateDebugReportCallback(instance, &vk.DebugReportCallbackCreateInfo{
 			SType:       vk.StructureTypeDebugReportCallbackCreateInfo,
 			Flags:       vk.DebugReportFlags(vk.DebugReportErrorBit | vk.DebugReportWarningBit),
 			PfnCallback: dbgCallbackFunc,
@@ -103,29 +219,44 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 
 	// Find a suitable GPU
 	var gpuCount uint32
-	ret = vk.EnumeratePhysicalDevices(p.instance, &gpuCount, nil)
+	ret = p.vkd.EnumeratePhysicalDevices(p.instance, &gpuCount, nil)
 	orPanic(NewError(ret))
 	if gpuCount == 0 {
 		return nil, errors.New("vulkan error: no GPU devices found")
 	}
 	gpus := make([]vk.PhysicalDevice, gpuCount)
-	ret = vk.EnumeratePhysicalDevices(p.instance, &gpuCount, gpus)
+	ret = p.vkd.EnumeratePhysicalDevices(p.instance, &gpuCount, gpus)
 	orPanic(NewError(ret))
 	// get the first one, multiple GPUs not supported yet
 	p.gpu = gpus[0]
-	vk.GetPhysicalDeviceProperties(p.gpu, &p.gpuProperties)
+	p.vkd.GetPhysicalDeviceProperties(p.gpu, &p.gpuProperties)
 	p.gpuProperties.Deref()
-	vk.GetPhysicalDeviceMemoryProperties(p.gpu, &p.memoryProperties)
+	p.vkd.GetPhysicalDeviceMemoryProperties(p.gpu, &p.memoryProperties)
 	p.memoryProperties.Deref()
 
+	// Negotiate the API version of the device, it's never higher than the version of the instance
+	p.apiVersion = negotiateVersion(instanceVersion, vk.Version(p.gpuProperties.ApiVersion))
+	if err := checkMinVersion("the device", p.apiVersion, minVersion); err != nil {
+		return nil, err
+	}
+	log.Printf("vulkan: using Vulkan %d.%d", p.apiVersion.Major(), p.apiVersion.Minor())
+
 	// Select device extensions
-	requiredDeviceExtensions := safeStrings(app.VulkanDeviceExtensions())
-	actualDeviceExtensions, err := DeviceExtensions(p.gpu)
+	requiredDeviceExtensions := dropPromoted(safeStrings(app.VulkanDeviceExtensions()), p.apiVersion)
+	if wsiExtensionsRequired(app) {
+		requiredDeviceExtensions = mergeExtensions(requiredDeviceExtensions, wsiDeviceExtensions()...)
+	}
+	actualDeviceExtensions, err := enumerateDeviceExtensions(p.vkd, p.gpu)
 	orPanic(err)
 	deviceExtensions, missing := checkExisting(actualDeviceExtensions, requiredDeviceExtensions)
 	if missing > 0 {
 		log.Println("vulkan warning: missing", missing, "required device extensions during init")
 	}
+	deviceExtensions, err = resolveDependencies(deviceExtensions, actualDeviceExtensions, instanceExtensions,
+		false, p.apiVersion, instanceVersion)
+	if err != nil {
+		return nil, err
+	}
 	log.Printf("vulkan: enabling %d device extensions", len(deviceExtensions))
 
 	// Make sure the surface is here if required
@@ -139,9 +270,9 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 
 	// Get queue family properties
 	var queueCount uint32
-	vk.GetPhysicalDeviceQueueFamilyProperties(p.gpu, &queueCount, nil)
+	p.vkd.GetPhysicalDeviceQueueFamilyProperties(p.gpu, &queueCount, nil)
 	queueProperties := make([]vk.QueueFamilyProperties, queueCount)
-	vk.GetPhysicalDeviceQueueFamilyProperties(p.gpu, &queueCount, queueProperties)
+	p.vkd.GetPhysicalDeviceQueueFamilyProperties(p.gpu, &queueCount, queueProperties)
 	if queueCount == 0 { // probably should try another GPU
 		return nil, errors.New("vulkan error: no queue families found on GPU 0")
 	}
@@ -149,7 +280,6 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 	// Find a suitable queue family for the target Vulkan mode
 	var graphicsFound bool
 	var presentFound bool
-	var separateQueue bool
 	for i := uint32(0); i < queueCount; i++ {
 		var (
 			required        vk.QueueFlags
@@ -158,8 +288,7 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 		)
 		if graphicsFound {
 			// looking for separate present queue
-			separateQueue = true
-			vk.GetPhysicalDeviceSurfaceSupport(p.gpu, i, p.surface, &supportsPresent)
+			p.vkd.GetPhysicalDeviceSurfaceSupport(p.gpu, i, p.surface, &supportsPresent)
 			if supportsPresent.B() {
 				p.presentQueueIndex = i
 				presentFound = true
@@ -174,13 +303,15 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 		}
 		if mode.Has(VulkanPresent) {
 			needsPresent = true
-			vk.GetPhysicalDeviceSurfaceSupport(p.gpu, i, p.surface, &supportsPresent)
+			p.vkd.GetPhysicalDeviceSurfaceSupport(p.gpu, i, p.surface, &supportsPresent)
 		}
 		queueProperties[i].Deref()
 		if queueProperties[i].QueueFlags&required != 0 {
 			if !needsPresent || (needsPresent && supportsPresent.B()) {
 				p.graphicsQueueIndex = i
+				p.presentQueueIndex = i
 				graphicsFound = true
+				presentFound = needsPresent
 				break
 			} else if needsPresent {
 				p.graphicsQueueIndex = i
@@ -190,23 +321,32 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 			}
 		}
 	}
-	if separateQueue && !presentFound {
-		err := errors.New("vulkan error: could not found separate queue with present capabilities")
-		return nil, err
-	}
 	if !graphicsFound {
 		err := errors.New("vulkan error: could not find a suitable queue family for the target Vulkan mode")
 		return nil, err
 	}
+	if mode.Has(VulkanPresent) && !presentFound {
+		err := errors.New("vulkan error: could not find a queue family with present capabilities")
+		return nil, err
+	}
 
-	// Create a Vulkan device
+	p.deviceExtensions = deviceExtensions
+	p.deviceLayers = validationLayers
+	p.createDevice()
+	p.initContext()
+	initialized = true
+	return p, nil
+}
+
+// createDevice creates the logical device with the queues of the selected families.
+func (p *platform) createDevice() {
 	queueInfos := []vk.DeviceQueueCreateInfo{{
 		SType:            vk.StructureTypeDeviceQueueCreateInfo,
 		QueueFamilyIndex: p.graphicsQueueIndex,
 		QueueCount:       1,
 		PQueuePriorities: []float32{1.0},
 	}}
-	if separateQueue {
+	if p.HasSeparatePresentQueue() {
 		queueInfos = append(queueInfos, vk.DeviceQueueCreateInfo{
 			SType:            vk.StructureTypeDeviceQueueCreateInfo,
 			QueueFamilyIndex: p.presentQueueIndex,
@@ -216,30 +356,52 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 	}
 
 	var device vk.Device
-	ret = vk.CreateDevice(p.gpu, &vk.DeviceCreateInfo{
+	ret := p.vkd.CreateDevice(p.gpu, &vk.DeviceCreateInfo{
 		SType:                   vk.StructureTypeDeviceCreateInfo,
 		QueueCreateInfoCount:    uint32(len(queueInfos)),
 		PQueueCreateInfos:       queueInfos,
-		EnabledExtensionCount:   uint32(len(deviceExtensions)),
-		PpEnabledExtensionNames: deviceExtensions,
-		EnabledLayerCount:       uint32(len(validationLayers)),
-		PpEnabledLayerNames:     validationLayers,
+		EnabledExtensionCount:   uint32(len(p.deviceExtensions)),
+		PpEnabledExtensionNames: p.deviceExtensions,
+		EnabledLayerCount:       uint32(len(p.deviceLayers)),
+		PpEnabledLayerNames:     p.deviceLayers,
 	}, nil, &device)
 	orPanic(NewError(ret))
 	p.device = device
-	p.context.device = device
-	app.VulkanInit(p.context)
+	registerDevice(p.device, p.vkd, p.apiVersion)
+
+	p.graphicsQueue = newQueue(p.vkd, p.device, p.graphicsQueueIndex, p.debugUtils)
+	p.presentQueue = p.graphicsQueue
+	setObjectName(p.device, p.debugUtils, p.graphicsQueue.queue, "graphics queue")
+	if p.HasSeparatePresentQueue() {
+		p.presentQueue = newQueue(p.vkd, p.device, p.presentQueueIndex, p.debugUtils)
+		setObjectName(p.device, p.debugUtils, p.presentQueue.queue, "present queue")
+	}
+}
 
-	var queue vk.Queue
-	vk.GetDeviceQueue(p.device, p.graphicsQueueIndex, 0, &queue)
-	p.graphicsQueue = queue
+// initContext initializes the context for the current device and lets the application
+// initialize its state, then prepares the swapchain if presenting.
+func (p *platform) initContext() {
+	app := p.app
+	mode := app.VulkanMode()
+	*p.context = context{
+		vkd:      p.vkd,
+		platform: p,
+		device:   p.device,
+		// TODO: make configurable
+		// defines count of slots allocated in swapchain
+		frameLag: 3,
+
+		sampleCount: vk.SampleCount1Bit,
+		validation:  p.validation,
+		debugUtils:  p.debugUtils,
+	}
+	if iface, ok := app.(ApplicationShaderWatcher); ok {
+		p.context.shaderWatcher = newShaderWatcher(p.device, p.DeviceWaitIdle,
+			iface.VulkanShaderWatchInterval())
+	}
+	app.VulkanInit(p.context)
 
 	if mode.Has(VulkanPresent) { // init a swapchain for surface
-		if separateQueue {
-			var presentQueue vk.Queue
-			vk.GetDeviceQueue(p.device, p.presentQueueIndex, 0, &presentQueue)
-			p.presentQueue = presentQueue
-		}
 		p.context.preparePresent()
 
 		dimensions := &SwapchainDimensions{
@@ -250,7 +412,33 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 		if iface, ok := app.(ApplicationSwapchainDimensions); ok {
 			dimensions = iface.VulkanSwapchainDimensions()
 		}
-		p.context.prepareSwapchain(p.gpu, p.surface, dimensions)
+		p.context.requestedDimensions = dimensions
+		p.context.suspended = !p.context.prepareSwapchain(p.gpu, p.surface, dimensions)
+	}
+	if iface, ok := app.(ApplicationRenderPass); ok && mode.Has(VulkanPresent) {
+		p.context.renderPassOptions = iface.VulkanRenderPass()
+	}
+	if iface, ok := app.(ApplicationDepthStencil); ok && mode.Has(VulkanPresent) {
+		p.context.depthOptions = iface.VulkanDepthStencil()
+	}
+	if iface, ok := app.(ApplicationMultisample); ok && mode.Has(VulkanPresent) {
+		limits := p.gpuProperties.Limits
+		limits.Deref()
+		supported := []vk.SampleCountFlags{limits.FramebufferColorSampleCounts}
+		if p.context.depthOptions != nil {
+			supported = append(supported, limits.FramebufferDepthSampleCounts)
+		}
+		requested := iface.VulkanSampleCount()
+		p.context.sampleCount = ClampSampleCount(requested, supported...)
+		if p.context.sampleCount != requested {
+			log.Printf("vulkan warning: sample count %d is not supported, using %d",
+				requested, p.context.sampleCount)
+		}
+		opts := p.context.renderPassOptions
+		if p.context.sampleCount > vk.SampleCount1Bit && opts != nil && opts.ColorLoadOp == vk.AttachmentLoadOpLoad {
+			orPanic(errors.New("vulkan error: the multisampled color attachment cannot be loaded, " +
+				"use AttachmentLoadOpClear or AttachmentLoadOpDontCare"))
+		}
 	}
 	if iface, ok := app.(ApplicationContextPrepare); ok {
 		p.context.SetOnPrepare(iface.VulkanContextPrepare)
@@ -261,13 +449,13 @@ func NewPlatform(app Application) (pFace Platform, err error) {
 	if iface, ok := app.(ApplicationContextInvalidate); ok {
 		p.context.SetOnInvalidate(iface.VulkanContextInvalidate)
 	}
-	if mode.Has(VulkanPresent) {
+	if mode.Has(VulkanPresent) && !p.context.suspended {
 		p.context.prepare(false)
 	}
-	return p, nil
 }
 
 type basePlatform struct {
+	vkd     driver.Driver
 	context *context
 
 	instance vk.Instance
@@ -276,11 +464,13 @@ type basePlatform struct {
 
 	graphicsQueueIndex uint32
 	presentQueueIndex  uint32
-	presentQueue       vk.Queue
-	graphicsQueue      vk.Queue
+	presentQueue       *Queue
+	graphicsQueue      *Queue
 
 	gpuProperties    vk.PhysicalDeviceProperties
 	memoryProperties vk.PhysicalDeviceMemoryProperties
+	apiVersion       vk.Version
+	allocator        *hostAllocator
 }
 
 func (p *basePlatform) MemoryProperties() vk.PhysicalDeviceMemoryProperties {
@@ -291,6 +481,17 @@ func (p *basePlatform) PhysicalDeviceProperies() vk.PhysicalDeviceProperties {
 	return p.gpuProperties
 }
 
+func (p *basePlatform) APIVersion() vk.Version {
+	return p.apiVersion
+}
+
+func (p *basePlatform) AllocationStats() *AllocationStats {
+	if p.allocator == nil {
+		return nil
+	}
+	return p.allocator.stats()
+}
+
 func (p *basePlatform) PhysicalDevice() vk.PhysicalDevice {
 	return p.gpu
 }
@@ -312,16 +513,31 @@ func (p *basePlatform) HasSeparatePresentQueue() bool {
 }
 
 func (p *basePlatform) GraphicsQueue() vk.Queue {
-	return p.graphicsQueue
+	return p.graphicsQueue.Queue()
 }
 
 func (p *basePlatform) PresentQueue() vk.Queue {
-	if p.graphicsQueueIndex != p.presentQueueIndex {
-		return p.presentQueue
-	}
+	return p.presentQueue.Queue()
+}
+
+func (p *basePlatform) SyncGraphicsQueue() *Queue {
 	return p.graphicsQueue
 }
 
+func (p *basePlatform) SyncPresentQueue() *Queue {
+	return p.presentQueue
+}
+
+func (p *basePlatform) DeviceWaitIdle() error {
+	p.graphicsQueue.mu.Lock()
+	defer p.graphicsQueue.mu.Unlock()
+	if p.presentQueue != p.graphicsQueue {
+		p.presentQueue.mu.Lock()
+		defer p.presentQueue.mu.Unlock()
+	}
+	return NewError(p.vkd.DeviceWaitIdle(p.device))
+}
+
 func (p *basePlatform) Instance() vk.Instance {
 	return p.instance
 }
@@ -333,35 +549,123 @@ func (p *basePlatform) Device() vk.Device {
 type platform struct {
 	basePlatform
 
+	app           Application
 	surface       vk.Surface
 	debugCallback vk.DebugReportCallback
+
+	deviceExtensions []string
+	deviceLayers     []string
+	validation       *validation
+	debugUtils       bool
+	trace            *trace.Writer
+	leaks            *leakTracker
 }
 
 func (p *platform) Surface() vk.Surface {
 	return p.surface
 }
 
-func (p *platform) Destroy() {
-	if p.device != nil {
-		vk.DeviceWaitIdle(p.device)
+func (p *platform) DetachSurface() {
+	if p.surface == vk.NullSurface {
+		return
+	}
+	p.context.releaseSwapchain()
+	p.vkd.DestroySurface(p.instance, p.surface, nil)
+	p.surface = vk.NullSurface
+}
+
+func (p *platform) AttachSurface() error {
+	if !p.app.VulkanMode().Has(VulkanPresent) {
+		return errors.New("vulkan error: surface is not used in the current Vulkan mode")
 	}
+	if p.surface != vk.NullSurface {
+		return errors.New("vulkan error: surface is attached already")
+	}
+	surface := p.app.VulkanSurface(p.instance)
+	if surface == vk.NullSurface {
+		return errors.New("vulkan error: surface required but not provided")
+	}
+	var supportsPresent vk.Bool32
+	p.vkd.GetPhysicalDeviceSurfaceSupport(p.gpu, p.presentQueueIndex, surface, &supportsPresent)
+	if !supportsPresent.B() {
+		p.vkd.DestroySurface(p.instance, surface, nil)
+		return errors.New("vulkan error: surface is not supported by the present queue family")
+	}
+	p.surface = surface
+	return p.context.attachSwapchain()
+}
+
+func (p *platform) RecoverDevice() error {
+	retries := DefaultDeviceLostRetries
+	iface, notify := p.app.(ApplicationDeviceLost)
+	if notify {
+		retries = iface.VulkanDeviceRetries()
+	}
+	for attempt := 1; ; attempt++ {
+		if notify {
+			if err := iface.VulkanDeviceLost(attempt); err != nil {
+				return err
+			}
+		}
+		err := p.recreateDevice()
+		if err == nil {
+			log.Printf("vulkan: device recovered after %d attempt(s)", attempt)
+			return nil
+		}
+		if !errors.Is(err, ErrDeviceLost) || attempt >= retries {
+			return err
+		}
+		log.Printf("vulkan warning: device recovery attempt %d failed: %v", attempt, err)
+	}
+}
+
+func (p *platform) recreateDevice() (err error) {
+	defer checkErr(&err)
+	p.destroyDevice()
+	p.createDevice()
+	p.initContext()
+	return nil
+}
+
+// destroyDevice destroys the context and the logical device, keeping the instance and the surface.
+func (p *platform) destroyDevice() {
+	if p.device == nil {
+		return
+	}
+	// fails if the device is lost, but all the work is done anyway
+	p.DeviceWaitIdle()
 	p.context.destroy()
+	p.vkd.DestroyDevice(p.device, nil)
+	unregisterDevice(p.device)
+	p.device = nil
+}
+
+func (p *platform) Destroy() {
+	p.destroyDevice()
 	p.context = nil
 	if p.surface != vk.NullSurface {
-		vk.DestroySurface(p.instance, p.surface, nil)
+		p.vkd.DestroySurface(p.instance, p.surface, nil)
 		p.surface = vk.NullSurface
 	}
-	if p.device != nil {
-		vk.DestroyDevice(p.device, nil)
-		p.device = nil
-	}
 	if p.debugCallback != vk.NullDebugReportCallback {
-		vk.DestroyDebugReportCallback(p.instance, p.debugCallback, nil)
+		p.vkd.DestroyDebugReportCallback(p.instance, p.debugCallback, nil)
 	}
 	if p.instance != nil {
-		vk.DestroyInstance(p.instance, nil)
+		p.vkd.DestroyInstance(p.instance, nil)
 		p.instance = nil
 	}
+	if p.allocator != nil {
+		p.allocator.release()
+	}
+	if p.leaks != nil {
+		if leaks := p.leaks.leaks(); len(leaks) > 0 {
+			p.app.(ApplicationLeakTracker).VulkanLeaks(leaks)
+		}
+		p.leaks = nil
+	}
+	if p.trace != nil {
+		flushTrace(p.trace)
+	}
 }
 
 func dbgCallbackFunc(flags vk.DebugReportFlags, objectType vk.DebugReportObjectType,
@@ -0,0 +1,432 @@
+package asche
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// fakeApp is an application presenting to the surface of a fake driver.
+type fakeApp struct {
+	BaseVulkanApp
+	fd         *fake.Driver
+	dimensions *SwapchainDimensions
+}
+
+func (a *fakeApp) VulkanSurface(instance vk.Instance) vk.Surface {
+	return a.fd.CreateSurface()
+}
+
+func (a *fakeApp) VulkanSwapchainDimensions() *SwapchainDimensions {
+	if a.dimensions != nil {
+		return a.dimensions
+	}
+	return &SwapchainDimensions{
+		Width: 640, Height: 480,
+		Format: vk.FormatB8g8r8a8Unorm,
+	}
+}
+
+// swapchainRecorder records the create info of every swapchain created by the fake driver.
+type swapchainRecorder struct {
+	*fake.Driver
+	created []vk.SwapchainCreateInfo
+}
+
+func (r *swapchainRecorder) CreateSwapchain(device vk.Device, pCreateInfo *vk.SwapchainCreateInfo,
+	pAllocator *vk.AllocationCallbacks, pSwapchain *vk.Swapchain) vk.Result {
+
+	r.created = append(r.created, *pCreateInfo)
+	return r.Driver.CreateSwapchain(device, pCreateInfo, pAllocator, pSwapchain)
+}
+
+// newFakePlatform creates a platform for app on top of the fake driver of app,
+// it's destroyed and checked for leaks when the test ends.
+func newFakePlatform(t *testing.T, app *fakeApp) (*swapchainRecorder, Platform) {
+	t.Helper()
+	r := &swapchainRecorder{Driver: app.fd}
+	prev := vkd
+	vkd = r
+	t.Cleanup(func() { vkd = prev })
+
+	p, err := NewPlatform(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		p.Destroy()
+		if live := app.fd.Live(); len(live) > 0 {
+			t.Errorf("objects alive after Destroy: %v", live)
+		}
+	})
+	return r, p
+}
+
+func countCalls(fd *fake.Driver, name string) int {
+	var n int
+	for _, call := range fd.Calls() {
+		if call == name {
+			n++
+		}
+	}
+	return n
+}
+
+// drawFrame begins and ends a frame, reporting whether either of them found the swapchain outdated.
+func drawFrame(t *testing.T, ctx Context) bool {
+	t.Helper()
+	frame, outdated, err := ctx.BeginFrame()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outdated {
+		return true
+	}
+	outdated, err = ctx.EndFrame(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return outdated
+}
+
+func queueFamily(flags vk.QueueFlagBits, present bool) fake.QueueFamily {
+	return fake.QueueFamily{
+		Properties: vk.QueueFamilyProperties{
+			QueueFlags: vk.QueueFlags(flags),
+			QueueCount: 1,
+		},
+		Present: present,
+	}
+}
+
+func TestQueueFamilySelection(t *testing.T) {
+	graphics := vk.QueueGraphicsBit | vk.QueueComputeBit | vk.QueueTransferBit
+	tests := []struct {
+		name     string
+		families []fake.QueueFamily
+		graphics uint32
+		present  uint32
+	}{
+		{
+			name:     "shared",
+			families: []fake.QueueFamily{queueFamily(graphics, true)},
+			graphics: 0, present: 0,
+		},
+		{
+			name: "graphics after transfer",
+			families: []fake.QueueFamily{
+				queueFamily(vk.QueueTransferBit, true),
+				queueFamily(graphics, true),
+			},
+			graphics: 1, present: 1,
+		},
+		{
+			name: "separate present",
+			families: []fake.QueueFamily{
+				queueFamily(graphics, false),
+				queueFamily(vk.QueueTransferBit, true),
+			},
+			graphics: 0, present: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			app.fd.Devices[0].QueueFamilies = tt.families
+			_, p := newFakePlatform(t, app)
+
+			if got := p.GraphicsQueueFamilyIndex(); got != tt.graphics {
+				t.Errorf("graphics queue family %d, want %d", got, tt.graphics)
+			}
+			if got := p.PresentQueueFamilyIndex(); got != tt.present {
+				t.Errorf("present queue family %d, want %d", got, tt.present)
+			}
+			if got, want := p.HasSeparatePresentQueue(), tt.graphics != tt.present; got != want {
+				t.Errorf("separate present queue %v, want %v", got, want)
+			}
+			if drawFrame(t, app.Context()) {
+				t.Error("frame outdated")
+			}
+		})
+	}
+}
+
+func TestQueueFamilyUnsupported(t *testing.T) {
+	tests := []struct {
+		name     string
+		families []fake.QueueFamily
+	}{
+		{
+			name:     "no graphics",
+			families: []fake.QueueFamily{queueFamily(vk.QueueTransferBit, true)},
+		},
+		{
+			name:     "no present",
+			families: []fake.QueueFamily{queueFamily(vk.QueueGraphicsBit, false)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd := fake.New()
+			fd.Devices[0].QueueFamilies = tt.families
+			prev := vkd
+			vkd = fd
+			defer func() { vkd = prev }()
+
+			if _, err := NewPlatform(&fakeApp{fd: fd}); err == nil {
+				t.Fatal("NewPlatform succeeded")
+			}
+			if live := fd.Live(); len(live) > 0 {
+				t.Errorf("objects alive after a failed NewPlatform: %v", live)
+			}
+		})
+	}
+}
+
+func TestSwapchainFormat(t *testing.T) {
+	srgb := vk.ColorSpaceSrgbNonlinear
+	tests := []struct {
+		name      string
+		formats   []vk.SurfaceFormat
+		requested vk.Format
+		want      vk.Format
+	}{
+		{
+			name:      "single",
+			formats:   []vk.SurfaceFormat{{Format: vk.FormatR8g8b8a8Unorm, ColorSpace: srgb}},
+			requested: vk.FormatB8g8r8a8Unorm,
+			want:      vk.FormatR8g8b8a8Unorm,
+		},
+		{
+			name:      "undefined",
+			formats:   []vk.SurfaceFormat{{Format: vk.FormatUndefined, ColorSpace: srgb}},
+			requested: vk.FormatR8g8b8a8Srgb,
+			want:      vk.FormatR8g8b8a8Srgb,
+		},
+		{
+			name: "first of many",
+			formats: []vk.SurfaceFormat{
+				{Format: vk.FormatB8g8r8a8Srgb, ColorSpace: srgb},
+				{Format: vk.FormatR8g8b8a8Unorm, ColorSpace: srgb},
+			},
+			requested: vk.FormatR8g8b8a8Unorm,
+			want:      vk.FormatB8g8r8a8Srgb,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{
+				fd: fake.New(),
+				dimensions: &SwapchainDimensions{
+					Width: 640, Height: 480,
+					Format: tt.requested,
+				},
+			}
+			app.fd.Surface.Formats = tt.formats
+			r, _ := newFakePlatform(t, app)
+
+			if len(r.created) != 1 {
+				t.Fatalf("%d swapchains created, want 1", len(r.created))
+			}
+			info := r.created[0]
+			if info.ImageFormat != tt.want {
+				t.Errorf("swapchain format %v, want %v", info.ImageFormat, tt.want)
+			}
+			if info.ImageColorSpace != srgb {
+				t.Errorf("swapchain color space %v, want %v", info.ImageColorSpace, srgb)
+			}
+			if got := app.Context().SwapchainDimensions().Format; got != tt.want {
+				t.Errorf("swapchain dimensions format %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSwapchainNoFormats(t *testing.T) {
+	fd := fake.New()
+	fd.Surface.Formats = nil
+	prev := vkd
+	vkd = fd
+	defer func() { vkd = prev }()
+
+	if _, err := NewPlatform(&fakeApp{fd: fd}); err == nil {
+		t.Fatal("NewPlatform succeeded without surface formats")
+	}
+}
+
+func TestSwapchainPresentMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max uint32
+		images   uint32
+	}{
+		{name: "one more than minimum", min: 2, max: 8, images: 3},
+		{name: "clamped to maximum", min: 2, max: 2, images: 2},
+		{name: "unbounded", min: 3, max: 0, images: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			app.fd.Surface.Capabilities.MinImageCount = tt.min
+			app.fd.Surface.Capabilities.MaxImageCount = tt.max
+			r, _ := newFakePlatform(t, app)
+
+			if len(r.created) != 1 {
+				t.Fatalf("%d swapchains created, want 1", len(r.created))
+			}
+			info := r.created[0]
+			if info.PresentMode != vk.PresentModeFifo {
+				t.Errorf("present mode %v, want FIFO", info.PresentMode)
+			}
+			if info.MinImageCount != tt.images {
+				t.Errorf("%d swapchain images, want %d", info.MinImageCount, tt.images)
+			}
+		})
+	}
+}
+
+func TestAcquireOutdated(t *testing.T) {
+	tests := []struct {
+		name   string
+		result vk.Result
+		// outdated reports whether the frame acquiring the image is outdated,
+		// otherwise the swapchain is recreated by the next one.
+		outdated bool
+	}{
+		{name: "out of date", result: vk.ErrorOutOfDate, outdated: true},
+		{name: "suboptimal", result: vk.Suboptimal, outdated: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			r, _ := newFakePlatform(t, app)
+			ctx := app.Context()
+
+			app.fd.AcquireResults = []vk.Result{tt.result}
+			if got := drawFrame(t, ctx); got != tt.outdated {
+				t.Fatalf("outdated %v, want %v", got, tt.outdated)
+			}
+			if !tt.outdated {
+				if !drawFrame(t, ctx) {
+					t.Fatal("frame after a suboptimal acquire not outdated")
+				}
+			}
+			if len(r.created) != 2 {
+				t.Fatalf("%d swapchains created, want 2", len(r.created))
+			}
+			if r.created[1].OldSwapchain == vk.NullSwapchain {
+				t.Error("swapchain recreated without the old swapchain")
+			}
+			if drawFrame(t, ctx) {
+				t.Error("frame outdated after swapchain recreation")
+			}
+		})
+	}
+}
+
+func TestPresentOutdated(t *testing.T) {
+	tests := []struct {
+		name   string
+		result vk.Result
+	}{
+		{name: "out of date", result: vk.ErrorOutOfDate},
+		{name: "suboptimal", result: vk.Suboptimal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			r, _ := newFakePlatform(t, app)
+			ctx := app.Context()
+
+			app.fd.PresentResults = []vk.Result{tt.result}
+			if !drawFrame(t, ctx) {
+				t.Fatal("frame not outdated")
+			}
+			if !drawFrame(t, ctx) {
+				t.Fatal("swapchain not recreated by the next frame")
+			}
+			if len(r.created) != 2 {
+				t.Fatalf("%d swapchains created, want 2", len(r.created))
+			}
+			if drawFrame(t, ctx) {
+				t.Error("frame outdated after swapchain recreation")
+			}
+		})
+	}
+}
+
+func TestSurfaceLost(t *testing.T) {
+	tests := []struct {
+		name    string
+		acquire bool
+	}{
+		{name: "acquire", acquire: true},
+		{name: "present", acquire: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			r, p := newFakePlatform(t, app)
+			ctx := app.Context()
+			lost := p.Surface()
+
+			if tt.acquire {
+				app.fd.AcquireResults = []vk.Result{vk.ErrorSurfaceLost}
+			} else {
+				app.fd.PresentResults = []vk.Result{vk.ErrorSurfaceLost}
+			}
+			if !drawFrame(t, ctx) {
+				t.Fatal("frame not outdated after the surface is lost")
+			}
+			if p.Surface() == lost || p.Surface() == vk.NullSurface {
+				t.Fatal("new surface not attached")
+			}
+			if ctx.Suspended() {
+				t.Fatal("context suspended with a new surface attached")
+			}
+			if n := countCalls(app.fd, "DestroySurface"); n != 1 {
+				t.Errorf("%d surfaces destroyed, want 1", n)
+			}
+			if len(r.created) != 2 {
+				t.Errorf("%d swapchains created, want 2", len(r.created))
+			}
+			if drawFrame(t, ctx) {
+				t.Error("frame outdated on the new surface")
+			}
+		})
+	}
+}
+
+func TestDeviceLost(t *testing.T) {
+	for _, call := range []string{"AcquireNextImage", "QueueSubmit", "QueuePresent"} {
+		t.Run(call, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			_, p := newFakePlatform(t, app)
+			ctx := app.Context()
+
+			app.fd.Fail = func(name string) vk.Result {
+				if name == call {
+					return vk.ErrorDeviceLost
+				}
+				return vk.Success
+			}
+			frame, _, err := ctx.BeginFrame()
+			if err == nil {
+				_, err = ctx.EndFrame(frame)
+			}
+			if !errors.Is(err, ErrDeviceLost) {
+				t.Fatalf("got %v, want ErrDeviceLost", err)
+			}
+
+			app.fd.Fail = nil
+			if err := p.RecoverDevice(); err != nil {
+				t.Fatal(err)
+			}
+			if drawFrame(t, app.Context()) {
+				t.Error("frame outdated after device recovery")
+			}
+		})
+	}
+}
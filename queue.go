@@ -0,0 +1,64 @@
+package asche
+
+import (
+	"sync"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// Queue is a device queue that serializes submissions, presentation and waits,
+// since Vulkan requires access to a queue to be externally synchronized.
+// It's safe to use from multiple goroutines.
+type Queue struct {
+	vkd        driver.Driver
+	mu         sync.Mutex
+	queue      vk.Queue
+	family     uint32
+	debugUtils bool
+}
+
+func newQueue(d driver.Driver, device vk.Device, family uint32, debugUtils bool) *Queue {
+	var queue vk.Queue
+	d.GetDeviceQueue(device, family, 0, &queue)
+	return &Queue{
+		vkd:        d,
+		queue:      queue,
+		family:     family,
+		debugUtils: debugUtils,
+	}
+}
+
+// Queue gets the underlying queue handle, it must not be used to submit work directly.
+func (q *Queue) Queue() vk.Queue {
+	return q.queue
+}
+
+// Family gets the queue family index of the queue.
+func (q *Queue) Family() uint32 {
+	return q.family
+}
+
+// Submit submits command buffers to the queue.
+func (q *Queue) Submit(submits []vk.SubmitInfo, fence vk.Fence) error {
+	q.mu.Lock()
+	ret := q.vkd.QueueSubmit(q.queue, uint32(len(submits)), submits, fence)
+	q.mu.Unlock()
+	return NewError(ret)
+}
+
+// Present queues images for presentation, the result is returned as is
+// since vk.Suboptimal and vk.ErrorOutOfDate are expected to be handled by the caller.
+func (q *Queue) Present(info *vk.PresentInfo) vk.Result {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.vkd.QueuePresent(q.queue, info)
+}
+
+// WaitIdle waits for the queue to become idle.
+func (q *Queue) WaitIdle() error {
+	q.mu.Lock()
+	ret := q.vkd.QueueWaitIdle(q.queue)
+	q.mu.Unlock()
+	return NewError(ret)
+}
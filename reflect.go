@@ -0,0 +1,153 @@
+package asche
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vulkan-go/asche/spirv"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// ShaderStage gets the Vulkan shader stage corresponding to a SPIR-V execution model.
+func ShaderStage(model spirv.ExecutionModel) vk.ShaderStageFlagBits {
+	switch model {
+	case spirv.ExecutionModelVertex:
+		return vk.ShaderStageVertexBit
+	case spirv.ExecutionModelTessellationControl:
+		return vk.ShaderStageTessellationControlBit
+	case spirv.ExecutionModelTessellationEvaluation:
+		return vk.ShaderStageTessellationEvaluationBit
+	case spirv.ExecutionModelGeometry:
+		return vk.ShaderStageGeometryBit
+	case spirv.ExecutionModelFragment:
+		return vk.ShaderStageFragmentBit
+	case spirv.ExecutionModelGLCompute:
+		return vk.ShaderStageComputeBit
+	default:
+		return 0
+	}
+}
+
+// ShaderStages gets the combined shader stages of all entry points in the module.
+func ShaderStages(m *spirv.Module) vk.ShaderStageFlags {
+	var stages vk.ShaderStageFlags
+	for _, ep := range m.EntryPoints {
+		stages |= vk.ShaderStageFlags(ShaderStage(ep.ExecutionModel))
+	}
+	return stages
+}
+
+// VertexInputDescriptions derives vertex input state from the inputs of a vertex entry point,
+// assuming that all attributes are tightly packed in location order within a single
+// interleaved vertex buffer bound at the given binding.
+func VertexInputDescriptions(ep *spirv.EntryPoint, binding uint32) (
+	vk.VertexInputBindingDescription, []vk.VertexInputAttributeDescription, error) {
+
+	var offset uint32
+	attributes := make([]vk.VertexInputAttributeDescription, 0, len(ep.Inputs))
+	for _, in := range ep.Inputs {
+		size := in.Format.Size()
+		if size == 0 {
+			err := fmt.Errorf("vulkan error: unsupported format of vertex input %q at location %d",
+				in.Name, in.Location)
+			return vk.VertexInputBindingDescription{}, nil, err
+		}
+		attributes = append(attributes, vk.VertexInputAttributeDescription{
+			Location: in.Location,
+			Binding:  binding,
+			Format:   vk.Format(in.Format),
+			Offset:   offset,
+		})
+		offset += size
+	}
+	return vk.VertexInputBindingDescription{
+		Binding:   binding,
+		Stride:    offset,
+		InputRate: vk.VertexInputRateVertex,
+	}, attributes, nil
+}
+
+// DescriptorSetLayoutBindings merges descriptor bindings of the shader modules into
+// per-set layout bindings, with stage flags covering every module that uses a binding.
+// Runtime-sized arrays are reported with a descriptor count of 1.
+func DescriptorSetLayoutBindings(modules ...*spirv.Module) (map[uint32][]vk.DescriptorSetLayoutBinding, error) {
+	type key struct {
+		set, binding uint32
+	}
+	merged := make(map[key]*vk.DescriptorSetLayoutBinding)
+	for _, m := range modules {
+		stages := ShaderStages(m)
+		for _, b := range m.DescriptorBindings {
+			count := b.Count
+			if count == 0 {
+				count = 1
+			}
+			k := key{b.Set, b.Binding}
+			if existing, ok := merged[k]; ok {
+				if existing.DescriptorType != vk.DescriptorType(b.Type) || existing.DescriptorCount != count {
+					err := fmt.Errorf("vulkan error: conflicting declarations of set %d binding %d (%s)",
+						b.Set, b.Binding, b.Name)
+					return nil, err
+				}
+				existing.StageFlags |= stages
+				continue
+			}
+			merged[k] = &vk.DescriptorSetLayoutBinding{
+				Binding:         b.Binding,
+				DescriptorType:  vk.DescriptorType(b.Type),
+				DescriptorCount: count,
+				StageFlags:      stages,
+			}
+		}
+	}
+	sets := make(map[uint32][]vk.DescriptorSetLayoutBinding)
+	for k, b := range merged {
+		sets[k.set] = append(sets[k.set], *b)
+	}
+	for _, bindings := range sets {
+		sort.Slice(bindings, func(i, j int) bool {
+			return bindings[i].Binding < bindings[j].Binding
+		})
+	}
+	return sets, nil
+}
+
+// PushConstantRanges derives push-constant ranges from the push-constant blocks of the modules.
+// Identical ranges used by several modules are merged into one range with combined stage flags.
+func PushConstantRanges(modules ...*spirv.Module) []vk.PushConstantRange {
+	var ranges []vk.PushConstantRange
+	for _, m := range modules {
+		stages := ShaderStages(m)
+	blocks:
+		for _, block := range m.PushConstants {
+			for i := range ranges {
+				if ranges[i].Offset == block.Offset && ranges[i].Size == block.Size {
+					ranges[i].StageFlags |= stages
+					continue blocks
+				}
+			}
+			ranges = append(ranges, vk.PushConstantRange{
+				StageFlags: stages,
+				Offset:     block.Offset,
+				Size:       block.Size,
+			})
+		}
+	}
+	return ranges
+}
+
+// SpecializationMapEntries lays out the specialization constants of the module
+// sequentially in ID order, returning the map entries and the required data size.
+func SpecializationMapEntries(m *spirv.Module) ([]vk.SpecializationMapEntry, uint32) {
+	var offset uint32
+	entries := make([]vk.SpecializationMapEntry, 0, len(m.SpecConstants))
+	for _, c := range m.SpecConstants {
+		entries = append(entries, vk.SpecializationMapEntry{
+			ConstantID: c.ID,
+			Offset:     offset,
+			Size:       uint(c.Size),
+		})
+		offset += c.Size
+	}
+	return entries, offset
+}
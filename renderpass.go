@@ -0,0 +1,202 @@
+package asche
+
+import (
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// RenderPassOptions configures the default render pass owned by the context.
+type RenderPassOptions struct {
+	// ColorLoadOp is the load operation of the swapchain color attachment.
+	// With multisampling it must be Clear or DontCare, the multisampled color target
+	// is transient and has no previous contents to load, NewPlatform fails otherwise.
+	ColorLoadOp vk.AttachmentLoadOp
+	// ColorStoreOp is the store operation of the swapchain color attachment.
+	// With multisampling it applies to the resolved swapchain image, while the
+	// multisampled color target is never stored.
+	ColorStoreOp vk.AttachmentStoreOp
+	// ClearColor is the clear value of the color attachment.
+	ClearColor [4]float32
+
+	// DepthLoadOp is the load operation of the depth aspect of the depth/stencil attachment.
+	DepthLoadOp vk.AttachmentLoadOp
+	// DepthStoreOp is the store operation of the depth aspect of the depth/stencil attachment.
+	DepthStoreOp vk.AttachmentStoreOp
+	// StencilLoadOp is the load operation of the stencil aspect of the depth/stencil attachment.
+	StencilLoadOp vk.AttachmentLoadOp
+	// StencilStoreOp is the store operation of the stencil aspect of the depth/stencil attachment.
+	StencilStoreOp vk.AttachmentStoreOp
+	// ClearDepth is the clear value of the depth aspect.
+	ClearDepth float32
+	// ClearStencil is the clear value of the stencil aspect.
+	ClearStencil uint32
+}
+
+// DefaultRenderPassOptions clears the color attachment to opaque black and stores the result,
+// the depth/stencil attachment (if enabled) is cleared to the far plane and not stored.
+var DefaultRenderPassOptions = RenderPassOptions{
+	ColorLoadOp:    vk.AttachmentLoadOpClear,
+	ColorStoreOp:   vk.AttachmentStoreOpStore,
+	ClearColor:     [4]float32{0, 0, 0, 1},
+	DepthLoadOp:    vk.AttachmentLoadOpClear,
+	DepthStoreOp:   vk.AttachmentStoreOpDontCare,
+	StencilLoadOp:  vk.AttachmentLoadOpClear,
+	StencilStoreOp: vk.AttachmentStoreOpDontCare,
+	ClearDepth:     1,
+}
+
+// prepareRenderPass creates the default render pass unless there is
+// a compatible one already, so pipelines survive swapchain recreation.
+// Attachments are the color target, the depth/stencil image if enabled and,
+// when multisampling, the swapchain image the color target resolves to.
+func (c *context) prepareRenderPass() {
+	format := c.swapchainDimensions.Format
+	if c.renderPass != vk.NullRenderPass {
+		if c.renderPassFormat == format {
+			return
+		}
+		c.vkd.DestroyRenderPass(c.device, c.renderPass, nil)
+		c.renderPass = vk.NullRenderPass
+	}
+	opts := c.renderPassOptions
+	multisampled := c.colorImage != nil
+
+	// The previous contents of swapchain images are undefined unless loaded.
+	initialLayout := vk.ImageLayoutUndefined
+	if opts.ColorLoadOp == vk.AttachmentLoadOpLoad && !multisampled {
+		initialLayout = vk.ImageLayoutPresentSrc
+	}
+	color := vk.AttachmentDescription{
+		Format:         format,
+		Samples:        vk.SampleCount1Bit,
+		LoadOp:         opts.ColorLoadOp,
+		StoreOp:        opts.ColorStoreOp,
+		StencilLoadOp:  vk.AttachmentLoadOpDontCare,
+		StencilStoreOp: vk.AttachmentStoreOpDontCare,
+		InitialLayout:  initialLayout,
+		FinalLayout:    vk.ImageLayoutPresentSrc,
+	}
+	if multisampled {
+		// only the resolved image is stored
+		color.Samples = c.sampleCount
+		color.StoreOp = vk.AttachmentStoreOpDontCare
+		color.FinalLayout = vk.ImageLayoutColorAttachmentOptimal
+	}
+	attachments := []vk.AttachmentDescription{color}
+	colorRefs := []vk.AttachmentReference{{
+		Attachment: 0,
+		Layout:     vk.ImageLayoutColorAttachmentOptimal,
+	}}
+	subpass := vk.SubpassDescription{
+		PipelineBindPoint:    vk.PipelineBindPointGraphics,
+		ColorAttachmentCount: uint32(len(colorRefs)),
+		PColorAttachments:    colorRefs,
+	}
+	dependency := vk.SubpassDependency{
+		SrcSubpass:    vk.SubpassExternal,
+		DstSubpass:    0,
+		SrcStageMask:  vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+		DstStageMask:  vk.PipelineStageFlags(vk.PipelineStageColorAttachmentOutputBit),
+		DstAccessMask: vk.AccessFlags(vk.AccessColorAttachmentReadBit | vk.AccessColorAttachmentWriteBit),
+	}
+	if c.depthImage != nil {
+		depthInitialLayout := vk.ImageLayoutUndefined
+		if opts.DepthLoadOp == vk.AttachmentLoadOpLoad || opts.StencilLoadOp == vk.AttachmentLoadOpLoad {
+			depthInitialLayout = vk.ImageLayoutDepthStencilAttachmentOptimal
+		}
+		subpass.PDepthStencilAttachment = &vk.AttachmentReference{
+			Attachment: uint32(len(attachments)),
+			Layout:     vk.ImageLayoutDepthStencilAttachmentOptimal,
+		}
+		attachments = append(attachments, vk.AttachmentDescription{
+			Format:         c.depthFormat,
+			Samples:        c.sampleCount,
+			LoadOp:         opts.DepthLoadOp,
+			StoreOp:        opts.DepthStoreOp,
+			StencilLoadOp:  opts.StencilLoadOp,
+			StencilStoreOp: opts.StencilStoreOp,
+			InitialLayout:  depthInitialLayout,
+			FinalLayout:    vk.ImageLayoutDepthStencilAttachmentOptimal,
+		})
+		dependency.SrcStageMask |= vk.PipelineStageFlags(vk.PipelineStageLateFragmentTestsBit)
+		dependency.DstStageMask |= vk.PipelineStageFlags(vk.PipelineStageEarlyFragmentTestsBit)
+		dependency.SrcAccessMask |= vk.AccessFlags(vk.AccessDepthStencilAttachmentWriteBit)
+		dependency.DstAccessMask |= vk.AccessFlags(vk.AccessDepthStencilAttachmentReadBit |
+			vk.AccessDepthStencilAttachmentWriteBit)
+	}
+	if multisampled {
+		subpass.PResolveAttachments = []vk.AttachmentReference{{
+			Attachment: uint32(len(attachments)),
+			Layout:     vk.ImageLayoutColorAttachmentOptimal,
+		}}
+		attachments = append(attachments, vk.AttachmentDescription{
+			Format:         format,
+			Samples:        vk.SampleCount1Bit,
+			LoadOp:         vk.AttachmentLoadOpDontCare,
+			StoreOp:        opts.ColorStoreOp,
+			StencilLoadOp:  vk.AttachmentLoadOpDontCare,
+			StencilStoreOp: vk.AttachmentStoreOpDontCare,
+			InitialLayout:  vk.ImageLayoutUndefined,
+			FinalLayout:    vk.ImageLayoutPresentSrc,
+		})
+	}
+
+	var renderPass vk.RenderPass
+	ret := c.vkd.CreateRenderPass(c.device, &vk.RenderPassCreateInfo{
+		SType:           vk.StructureTypeRenderPassCreateInfo,
+		AttachmentCount: uint32(len(attachments)),
+		PAttachments:    attachments,
+		SubpassCount:    1,
+		PSubpasses:      []vk.SubpassDescription{subpass},
+		DependencyCount: 1,
+		PDependencies:   []vk.SubpassDependency{dependency},
+	}, nil, &renderPass)
+	orPanic(NewError(ret))
+	c.renderPass = renderPass
+	c.nameObject(c.renderPass, "default render pass")
+	c.renderPassFormat = format
+
+	c.clearValues = make([]vk.ClearValue, len(attachments))
+	c.clearValues[0].SetColor(opts.ClearColor[:])
+	if c.depthImage != nil {
+		c.clearValues[1].SetDepthStencil(opts.ClearDepth, opts.ClearStencil)
+	}
+}
+
+// prepareFramebuffers creates a framebuffer for every swapchain image
+// that is compatible with the default render pass.
+func (c *context) prepareFramebuffers() {
+	for i, res := range c.swapchainImageResources {
+		var attachments []vk.ImageView
+		if c.colorImage != nil {
+			attachments = append(attachments, c.colorImage.View)
+		} else {
+			attachments = append(attachments, res.view)
+		}
+		if c.depthImage != nil {
+			attachments = append(attachments, c.depthImage.View)
+		}
+		if c.colorImage != nil {
+			attachments = append(attachments, res.view)
+		}
+		var framebuffer vk.Framebuffer
+		ret := c.vkd.CreateFramebuffer(c.device, &vk.FramebufferCreateInfo{
+			SType:           vk.StructureTypeFramebufferCreateInfo,
+			RenderPass:      c.renderPass,
+			AttachmentCount: uint32(len(attachments)),
+			PAttachments:    attachments,
+			Width:           c.swapchainDimensions.Width,
+			Height:          c.swapchainDimensions.Height,
+			Layers:          1,
+		}, nil, &framebuffer)
+		orPanic(NewError(ret))
+		res.framebuffer = framebuffer
+		c.nameObject(framebuffer, "framebuffer %d", i)
+	}
+}
+
+func (c *context) destroyRenderPass() {
+	if c.renderPass != vk.NullRenderPass {
+		c.vkd.DestroyRenderPass(c.device, c.renderPass, nil)
+		c.renderPass = vk.NullRenderPass
+	}
+}
@@ -0,0 +1,223 @@
+package asche
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// DefaultShaderWatchInterval is used when the application requests a non-positive poll interval.
+var DefaultShaderWatchInterval = 500 * time.Millisecond
+
+// Shader is a shader module loaded from a SPIR-V file tracked by a ShaderWatcher.
+type Shader struct {
+	w       *ShaderWatcher
+	fsys    fs.FS
+	name    string
+	module  vk.ShaderModule
+	modTime time.Time
+	size    int64
+}
+
+// Module gets the current shader module, it changes after a successful reload.
+func (s *Shader) Module() vk.ShaderModule {
+	s.w.mu.Lock()
+	defer s.w.mu.Unlock()
+	return s.module
+}
+
+// Name gets the file name the shader was loaded from.
+func (s *Shader) Name() string {
+	return s.name
+}
+
+type shaderRebuild struct {
+	shaders []*Shader
+	rebuild func() error
+}
+
+// ShaderWatcher tracks SPIR-V files loaded through it and polls them for changes on disk.
+// Changed files are re-created as shader modules at a safe point between frames
+// (in AcquireNextImage), then the pipelines registered with OnReload are asked to rebuild.
+// A module that fails validation or creation is ignored, so the previous one stays in use.
+type ShaderWatcher struct {
+	device   vk.Device
+	waitIdle func() error
+
+	mu       sync.Mutex
+	shaders  []*Shader
+	changed  map[*Shader]bool
+	rebuilds []shaderRebuild
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newShaderWatcher(device vk.Device, waitIdle func() error, interval time.Duration) *ShaderWatcher {
+	if interval <= 0 {
+		interval = DefaultShaderWatchInterval
+	}
+	w := &ShaderWatcher{
+		device:   device,
+		waitIdle: waitIdle,
+		changed:  make(map[*Shader]bool),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.watch(interval)
+	return w
+}
+
+// LoadShaderModuleFile loads a shader module from the SPIR-V file at path and starts tracking it.
+func (w *ShaderWatcher) LoadShaderModuleFile(path string) (*Shader, error) {
+	return w.LoadShaderModuleFS(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+}
+
+// LoadShaderModuleFS loads a shader module from the named SPIR-V file of fsys and starts tracking it.
+func (w *ShaderWatcher) LoadShaderModuleFS(fsys fs.FS, name string) (*Shader, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	module, err := LoadShaderModuleFS(w.device, fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	s := &Shader{
+		w:       w,
+		fsys:    fsys,
+		name:    name,
+		module:  module,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+	w.mu.Lock()
+	w.shaders = append(w.shaders, s)
+	w.mu.Unlock()
+	return s, nil
+}
+
+// OnReload registers a rebuild callback for a pipeline that uses the given shaders.
+// The callback is invoked after any of them has been reloaded, with the device idle,
+// and should read the new modules via Shader.Module. If the callback fails,
+// it should keep the previous pipeline, the error is only logged.
+func (w *ShaderWatcher) OnReload(rebuild func() error, shaders ...*Shader) {
+	w.mu.Lock()
+	w.rebuilds = append(w.rebuilds, shaderRebuild{
+		shaders: shaders,
+		rebuild: rebuild,
+	})
+	w.mu.Unlock()
+}
+
+func (w *ShaderWatcher) watch(interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+func (w *ShaderWatcher) poll() {
+	w.mu.Lock()
+	shaders := make([]*Shader, len(w.shaders))
+	copy(shaders, w.shaders)
+	w.mu.Unlock()
+
+	for _, s := range shaders {
+		info, err := fs.Stat(s.fsys, s.name)
+		if err != nil {
+			// the file may be in the middle of being rewritten
+			continue
+		}
+		w.mu.Lock()
+		if !info.ModTime().Equal(s.modTime) || info.Size() != s.size {
+			s.modTime = info.ModTime()
+			s.size = info.Size()
+			w.changed[s] = true
+		}
+		w.mu.Unlock()
+	}
+}
+
+// apply re-creates the changed shader modules and rebuilds dependent pipelines,
+// it must be called between frames. It fails if the device cannot get idle.
+func (w *ShaderWatcher) apply() error {
+	w.mu.Lock()
+	if len(w.changed) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+	changed := w.changed
+	w.changed = make(map[*Shader]bool)
+	rebuilds := make([]shaderRebuild, len(w.rebuilds))
+	copy(rebuilds, w.rebuilds)
+	w.mu.Unlock()
+
+	var oldModules []vk.ShaderModule
+	reloaded := make(map[*Shader]bool)
+	for s := range changed {
+		module, err := LoadShaderModuleFS(w.device, s.fsys, s.name)
+		if err != nil {
+			// the error names the file
+			log.Println("vulkan warning: keeping previous shader module,", err)
+			continue
+		}
+		log.Printf("vulkan: reloaded shader module %s", s.name)
+		w.mu.Lock()
+		oldModules = append(oldModules, s.module)
+		s.module = module
+		w.mu.Unlock()
+		reloaded[s] = true
+	}
+	if len(reloaded) == 0 {
+		return nil
+	}
+	// pipelines don't reference shader modules after creation
+	defer func() {
+		for _, module := range oldModules {
+			deviceDriver(w.device).DestroyShaderModule(w.device, module, nil)
+		}
+	}()
+
+	// a lost device fails, but nothing is in flight then
+	if err := w.waitIdle(); err != nil {
+		return err
+	}
+	for _, r := range rebuilds {
+		for _, s := range r.shaders {
+			if !reloaded[s] {
+				continue
+			}
+			if err := r.rebuild(); err != nil {
+				log.Println("vulkan warning: failed to rebuild pipeline after shader reload:", err)
+			}
+			break
+		}
+	}
+	return nil
+}
+
+func (w *ShaderWatcher) destroy() {
+	close(w.stop)
+	<-w.done
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.shaders {
+		deviceDriver(w.device).DestroyShaderModule(w.device, s.module, nil)
+		s.module = vk.NullShaderModule
+	}
+	w.shaders = nil
+	w.rebuilds = nil
+}
@@ -0,0 +1,783 @@
+package spirv
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Module holds the reflection data of a SPIR-V module.
+type Module struct {
+	Header Header
+	// EntryPoints lists the entry points declared by the module.
+	EntryPoints []EntryPoint
+	// DescriptorBindings lists all descriptor-backed resources of the module,
+	// sorted by set and binding.
+	DescriptorBindings []DescriptorBinding
+	// PushConstants lists push-constant blocks, at most one per entry point.
+	PushConstants []Block
+	// SpecConstants lists specialization constants sorted by their constant ID.
+	SpecConstants []SpecConstant
+}
+
+// EntryPoint describes a single shader entry point.
+type EntryPoint struct {
+	Name           string
+	ExecutionModel ExecutionModel
+	// LocalSize is the local workgroup size of compute entry points.
+	LocalSize [3]uint32
+	// Inputs lists the user-defined stage inputs sorted by location,
+	// for vertex shaders these are the vertex attributes.
+	Inputs []Input
+}
+
+// Input is a stage input variable with an assigned location.
+// Matrices and arrays occupy consecutive locations, one Input each.
+type Input struct {
+	Name     string
+	Location uint32
+	Format   Format
+}
+
+// DescriptorBinding is a resource bound through a descriptor set.
+type DescriptorBinding struct {
+	Name    string
+	Set     uint32
+	Binding uint32
+	Type    DescriptorType
+	// Count is the number of descriptors in the binding,
+	// it is 1 for non-arrays and 0 for runtime-sized arrays.
+	Count uint32
+}
+
+// Block is the memory layout of a push-constant block.
+type Block struct {
+	Name string
+	// Offset is the offset of the first member within the push-constant range.
+	Offset uint32
+	// Size is the size of the range covered by members, starting at Offset.
+	Size    uint32
+	Members []BlockMember
+}
+
+// BlockMember is a single member of a Block.
+type BlockMember struct {
+	Name   string
+	Offset uint32
+	Size   uint32
+}
+
+// SpecConstant is a specialization constant declared with a SpecId decoration.
+type SpecConstant struct {
+	ID   uint32
+	Name string
+	// Size is the size of the constant data in bytes, booleans use 4 bytes as VkBool32.
+	Size uint32
+	// Default holds the raw bits of the default value.
+	Default uint64
+}
+
+const (
+	opName                         = 5
+	opMemberName                   = 6
+	opEntryPoint                   = 15
+	opExecutionMode                = 16
+	opTypeBool                     = 20
+	opTypeInt                      = 21
+	opTypeFloat                    = 22
+	opTypeVector                   = 23
+	opTypeMatrix                   = 24
+	opTypeImage                    = 25
+	opTypeSampler                  = 26
+	opTypeSampledImage             = 27
+	opTypeArray                    = 28
+	opTypeRuntimeArray             = 29
+	opTypeStruct                   = 30
+	opTypePointer                  = 32
+	opConstantTrue                 = 41
+	opConstantFalse                = 42
+	opConstant                     = 43
+	opConstantComposite            = 44
+	opSpecConstantTrue             = 48
+	opSpecConstantFalse            = 49
+	opSpecConstant                 = 50
+	opVariable                     = 59
+	opDecorate                     = 71
+	opMemberDecorate               = 72
+	opExecutionModeID              = 331
+	opTypeAccelerationStructureKHR = 5341
+)
+
+const (
+	decorationSpecID        = 1
+	decorationBlock         = 2
+	decorationBufferBlock   = 3
+	decorationArrayStride   = 6
+	decorationMatrixStride  = 7
+	decorationBuiltIn       = 11
+	decorationLocation      = 30
+	decorationBinding       = 33
+	decorationDescriptorSet = 34
+	decorationOffset        = 35
+)
+
+const (
+	storageUniformConstant = 0
+	storageInput           = 1
+	storageUniform         = 2
+	storagePushConstant    = 9
+	storageStorageBuffer   = 12
+)
+
+const (
+	executionModeLocalSize   = 17
+	executionModeLocalSizeID = 38
+	builtInWorkgroupSize     = 25
+	dimBuffer                = 5
+	dimSubpassData           = 6
+)
+
+// typeOperands is the minimum number of operands following the result ID of type declarations.
+var typeOperands = map[uint32]int{
+	opTypeInt:          2,
+	opTypeFloat:        1,
+	opTypeVector:       2,
+	opTypeMatrix:       2,
+	opTypeImage:        7,
+	opTypeSampledImage: 1,
+	opTypeArray:        2,
+	opTypeRuntimeArray: 1,
+	opTypePointer:      2,
+}
+
+// decorations holds the subset of decorations used by reflection.
+type decorations struct {
+	has          map[uint32]bool
+	specID       uint32
+	arrayStride  uint32
+	matrixStride uint32
+	builtIn      uint32
+	location     uint32
+	binding      uint32
+	set          uint32
+	offset       uint32
+}
+
+func (d *decorations) apply(kind uint32, args []uint32) {
+	if d.has == nil {
+		d.has = make(map[uint32]bool)
+	}
+	d.has[kind] = true
+	if len(args) == 0 {
+		return
+	}
+	switch kind {
+	case decorationSpecID:
+		d.specID = args[0]
+	case decorationArrayStride:
+		d.arrayStride = args[0]
+	case decorationMatrixStride:
+		d.matrixStride = args[0]
+	case decorationBuiltIn:
+		d.builtIn = args[0]
+	case decorationLocation:
+		d.location = args[0]
+	case decorationBinding:
+		d.binding = args[0]
+	case decorationDescriptorSet:
+		d.set = args[0]
+	case decorationOffset:
+		d.offset = args[0]
+	}
+}
+
+// instruction is a type or constant declaration. For types args are the operands
+// following the result ID, for constants args hold all operands.
+type instruction struct {
+	op   uint32
+	args []uint32
+}
+
+type variable struct {
+	id      uint32
+	typeID  uint32
+	storage uint32
+}
+
+type entryPoint struct {
+	EntryPoint
+	id         uint32
+	interfaces []uint32
+}
+
+type parser struct {
+	names       map[uint32]string
+	memberNames map[uint32]map[uint32]string
+	decorations map[uint32]*decorations
+	members     map[uint32]map[uint32]*decorations
+	defs        map[uint32]*instruction
+	constants   map[uint32]uint64
+	variables   []variable
+	entryPoints []*entryPoint
+	specIDs     []uint32
+}
+
+// Parse reflects a SPIR-V module given in its binary form.
+func Parse(data []byte) (*Module, error) {
+	words, err := Words(data)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWords(words)
+}
+
+// ParseWords reflects a SPIR-V module given as host-order words, see Words.
+func ParseWords(words []uint32) (*Module, error) {
+	header, err := ParseHeader(words)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{
+		names:       make(map[uint32]string),
+		memberNames: make(map[uint32]map[uint32]string),
+		decorations: make(map[uint32]*decorations),
+		members:     make(map[uint32]map[uint32]*decorations),
+		defs:        make(map[uint32]*instruction),
+		constants:   make(map[uint32]uint64),
+	}
+	var localSizeIDs = make(map[uint32][3]uint32)
+	for pos := HeaderWords; pos < len(words); {
+		count := int(words[pos] >> 16)
+		op := words[pos] & 0xffff
+		if count == 0 || pos+count > len(words) {
+			return nil, fmt.Errorf("spirv: malformed instruction at word %d", pos)
+		}
+		args := words[pos+1 : pos+count]
+		pos += count
+
+		switch op {
+		case opName:
+			if len(args) >= 1 {
+				p.names[args[0]], _ = literalString(args[1:])
+			}
+		case opMemberName:
+			if len(args) >= 2 {
+				if p.memberNames[args[0]] == nil {
+					p.memberNames[args[0]] = make(map[uint32]string)
+				}
+				p.memberNames[args[0]][args[1]], _ = literalString(args[2:])
+			}
+		case opEntryPoint:
+			if len(args) < 3 {
+				return nil, fmt.Errorf("spirv: malformed OpEntryPoint")
+			}
+			name, n := literalString(args[2:])
+			p.entryPoints = append(p.entryPoints, &entryPoint{
+				EntryPoint: EntryPoint{
+					Name:           name,
+					ExecutionModel: ExecutionModel(args[0]),
+				},
+				id:         args[1],
+				interfaces: args[2+n:],
+			})
+		case opExecutionMode, opExecutionModeID:
+			if len(args) < 5 {
+				continue
+			}
+			mode := args[1]
+			switch {
+			case op == opExecutionMode && mode == executionModeLocalSize:
+				if ep := p.entryPoint(args[0]); ep != nil {
+					copy(ep.LocalSize[:], args[2:5])
+				}
+			case op == opExecutionModeID && mode == executionModeLocalSizeID:
+				// constants may be declared later, resolve after the pass
+				localSizeIDs[args[0]] = [3]uint32{args[2], args[3], args[4]}
+			}
+		case opDecorate:
+			if len(args) >= 2 {
+				p.decoration(args[0]).apply(args[1], args[2:])
+			}
+		case opMemberDecorate:
+			if len(args) >= 3 {
+				p.memberDecoration(args[0], args[1]).apply(args[2], args[3:])
+			}
+		case opTypeBool, opTypeInt, opTypeFloat, opTypeVector, opTypeMatrix,
+			opTypeImage, opTypeSampler, opTypeSampledImage, opTypeArray,
+			opTypeRuntimeArray, opTypeStruct, opTypePointer, opTypeAccelerationStructureKHR:
+			if len(args) < 1+typeOperands[op] {
+				return nil, fmt.Errorf("spirv: malformed type declaration (opcode %d)", op)
+			}
+			p.defs[args[0]] = &instruction{op: op, args: args[1:]}
+		case opConstant, opSpecConstant:
+			if len(args) >= 3 {
+				value := uint64(args[2])
+				if len(args) >= 4 {
+					value |= uint64(args[3]) << 32
+				}
+				p.constants[args[1]] = value
+				p.defs[args[1]] = &instruction{op: op, args: args}
+			}
+			if op == opSpecConstant && len(args) >= 2 {
+				p.specIDs = append(p.specIDs, args[1])
+			}
+		case opConstantTrue, opConstantFalse, opSpecConstantTrue, opSpecConstantFalse:
+			if len(args) >= 2 {
+				if op == opConstantTrue || op == opSpecConstantTrue {
+					p.constants[args[1]] = 1
+				} else {
+					p.constants[args[1]] = 0
+				}
+				p.defs[args[1]] = &instruction{op: op, args: args}
+			}
+			if (op == opSpecConstantTrue || op == opSpecConstantFalse) && len(args) >= 2 {
+				p.specIDs = append(p.specIDs, args[1])
+			}
+		case opConstantComposite:
+			if len(args) >= 2 {
+				p.defs[args[1]] = &instruction{op: op, args: args}
+			}
+		case opVariable:
+			if len(args) >= 3 {
+				p.variables = append(p.variables, variable{
+					typeID:  args[0],
+					id:      args[1],
+					storage: args[2],
+				})
+			}
+		}
+	}
+	for id, sizes := range localSizeIDs {
+		if ep := p.entryPoint(id); ep != nil {
+			for i, constID := range sizes {
+				ep.LocalSize[i] = uint32(p.constants[constID])
+			}
+		}
+	}
+	if err := p.checkTypes(); err != nil {
+		return nil, err
+	}
+	if size, ok := p.workgroupSize(); ok {
+		for _, ep := range p.entryPoints {
+			ep.LocalSize = size
+		}
+	}
+
+	m := &Module{
+		Header: *header,
+	}
+	for _, ep := range p.entryPoints {
+		ep.Inputs = p.inputs(ep)
+		m.EntryPoints = append(m.EntryPoints, ep.EntryPoint)
+	}
+	m.DescriptorBindings = p.descriptorBindings()
+	m.PushConstants = p.pushConstants()
+	m.SpecConstants = p.specConstants()
+	return m, nil
+}
+
+// literalString decodes a nul-terminated string literal,
+// returning the string and the number of words it occupies.
+func literalString(words []uint32) (string, int) {
+	buf := make([]byte, 0, len(words)*4)
+	for i, w := range words {
+		for j := 0; j < 4; j++ {
+			b := byte(w >> (8 * j))
+			if b == 0 {
+				return string(buf), i + 1
+			}
+			buf = append(buf, b)
+		}
+	}
+	return string(buf), len(words)
+}
+
+func (p *parser) entryPoint(id uint32) *entryPoint {
+	for _, ep := range p.entryPoints {
+		if ep.id == id {
+			return ep
+		}
+	}
+	return nil
+}
+
+func (p *parser) decoration(id uint32) *decorations {
+	d, ok := p.decorations[id]
+	if !ok {
+		d = &decorations{}
+		p.decorations[id] = d
+	}
+	return d
+}
+
+func (p *parser) memberDecoration(id, member uint32) *decorations {
+	m, ok := p.members[id]
+	if !ok {
+		m = make(map[uint32]*decorations)
+		p.members[id] = m
+	}
+	d, ok := m[member]
+	if !ok {
+		d = &decorations{}
+		m[member] = d
+	}
+	return d
+}
+
+func (p *parser) has(id, kind uint32) bool {
+	d, ok := p.decorations[id]
+	return ok && d.has[kind]
+}
+
+// workgroupSize finds a constant decorated with the WorkgroupSize built-in,
+// which takes precedence over LocalSize execution modes.
+func (p *parser) workgroupSize() (size [3]uint32, ok bool) {
+	for id, d := range p.decorations {
+		if !d.has[decorationBuiltIn] || d.builtIn != builtInWorkgroupSize {
+			continue
+		}
+		def, found := p.defs[id]
+		if !found || def.op != opConstantComposite || len(def.args) < 5 {
+			continue
+		}
+		for i, constID := range def.args[2:5] {
+			size[i] = uint32(p.constants[constID])
+		}
+		return size, true
+	}
+	return size, false
+}
+
+// checkTypes rejects types defined in terms of themselves, which reflection would follow forever.
+// Pointers are not followed, a struct may legitimately refer to itself through one.
+func (p *parser) checkTypes() error {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[uint32]int)
+	var visit func(id uint32) error
+	visit = func(id uint32) error {
+		def, ok := p.defs[id]
+		if !ok || state[id] == visited {
+			return nil
+		}
+		if state[id] == visiting {
+			return fmt.Errorf("spirv: type %d is defined in terms of itself", id)
+		}
+		var elems []uint32
+		switch def.op {
+		case opTypeVector, opTypeMatrix, opTypeArray, opTypeRuntimeArray, opTypeSampledImage:
+			elems = def.args[:1]
+		case opTypeStruct:
+			elems = def.args
+		}
+		state[id] = visiting
+		for _, elem := range elems {
+			if err := visit(elem); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		return nil
+	}
+	for id := range p.defs {
+		if err := visit(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pointee resolves a pointer type to the type it points to.
+func (p *parser) pointee(typeID uint32) uint32 {
+	if def, ok := p.defs[typeID]; ok && def.op == opTypePointer && len(def.args) >= 2 {
+		return def.args[1]
+	}
+	return typeID
+}
+
+// unwrapArrays strips array types, returning the element type and total element count.
+// Runtime arrays result in a zero count. Cycles are rejected by checkTypes beforehand.
+func (p *parser) unwrapArrays(typeID uint32) (elem uint32, count uint32) {
+	count = 1
+	for {
+		def, ok := p.defs[typeID]
+		if !ok {
+			return typeID, count
+		}
+		switch def.op {
+		case opTypeArray:
+			count *= uint32(p.constants[def.args[1]])
+			typeID = def.args[0]
+		case opTypeRuntimeArray:
+			count = 0
+			typeID = def.args[0]
+		default:
+			return typeID, count
+		}
+	}
+}
+
+func (p *parser) descriptorBindings() []DescriptorBinding {
+	var bindings []DescriptorBinding
+	for _, v := range p.variables {
+		switch v.storage {
+		case storageUniformConstant, storageUniform, storageStorageBuffer:
+		default:
+			continue
+		}
+		d, ok := p.decorations[v.id]
+		if !ok || !d.has[decorationBinding] {
+			continue
+		}
+		elem, count := p.unwrapArrays(p.pointee(v.typeID))
+		descType, ok := p.descriptorType(v.storage, elem)
+		if !ok {
+			continue
+		}
+		name := p.names[v.id]
+		if name == "" {
+			name = p.names[elem]
+		}
+		bindings = append(bindings, DescriptorBinding{
+			Name:    name,
+			Set:     d.set,
+			Binding: d.binding,
+			Type:    descType,
+			Count:   count,
+		})
+	}
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].Set != bindings[j].Set {
+			return bindings[i].Set < bindings[j].Set
+		}
+		return bindings[i].Binding < bindings[j].Binding
+	})
+	return bindings
+}
+
+func (p *parser) descriptorType(storage, typeID uint32) (DescriptorType, bool) {
+	def, ok := p.defs[typeID]
+	if !ok {
+		return 0, false
+	}
+	switch storage {
+	case storageStorageBuffer:
+		return DescriptorTypeStorageBuffer, true
+	case storageUniform:
+		if p.has(typeID, decorationBufferBlock) {
+			return DescriptorTypeStorageBuffer, true
+		}
+		return DescriptorTypeUniformBuffer, true
+	}
+	switch def.op {
+	case opTypeSampler:
+		return DescriptorTypeSampler, true
+	case opTypeSampledImage:
+		return DescriptorTypeCombinedImageSampler, true
+	case opTypeAccelerationStructureKHR:
+		return DescriptorTypeAccelerationStructureKHR, true
+	case opTypeImage:
+		// args: sampled type, dim, depth, arrayed, ms, sampled, format
+		dim, sampled := def.args[1], def.args[5]
+		switch {
+		case dim == dimSubpassData:
+			return DescriptorTypeInputAttachment, true
+		case dim == dimBuffer && sampled == 2:
+			return DescriptorTypeStorageTexelBuffer, true
+		case dim == dimBuffer:
+			return DescriptorTypeUniformTexelBuffer, true
+		case sampled == 2:
+			return DescriptorTypeStorageImage, true
+		default:
+			return DescriptorTypeSampledImage, true
+		}
+	}
+	return 0, false
+}
+
+func (p *parser) pushConstants() []Block {
+	var blocks []Block
+	for _, v := range p.variables {
+		if v.storage != storagePushConstant {
+			continue
+		}
+		typeID := p.pointee(v.typeID)
+		def, ok := p.defs[typeID]
+		if !ok || def.op != opTypeStruct {
+			continue
+		}
+		block := Block{
+			Name: p.names[v.id],
+		}
+		if block.Name == "" {
+			block.Name = p.names[typeID]
+		}
+		var end uint32
+		for i, memberType := range def.args {
+			member := BlockMember{
+				Name: p.memberNames[typeID][uint32(i)],
+			}
+			var matrixStride uint32
+			if d, ok := p.members[typeID][uint32(i)]; ok {
+				member.Offset = d.offset
+				matrixStride = d.matrixStride
+			}
+			member.Size = p.sizeOf(memberType, matrixStride)
+			if i == 0 || member.Offset < block.Offset {
+				block.Offset = member.Offset
+			}
+			if member.Offset+member.Size > end {
+				end = member.Offset + member.Size
+			}
+			block.Members = append(block.Members, member)
+		}
+		block.Size = end - block.Offset
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// sizeOf computes the size of a type as laid out in an explicitly laid out block.
+func (p *parser) sizeOf(typeID uint32, matrixStride uint32) uint32 {
+	def, ok := p.defs[typeID]
+	if !ok {
+		return 0
+	}
+	switch def.op {
+	case opTypeBool:
+		return 4
+	case opTypeInt, opTypeFloat:
+		return def.args[0] / 8
+	case opTypeVector:
+		return def.args[1] * p.sizeOf(def.args[0], 0)
+	case opTypeMatrix:
+		if matrixStride > 0 {
+			return def.args[1] * matrixStride
+		}
+		return def.args[1] * p.sizeOf(def.args[0], 0)
+	case opTypeArray:
+		length := uint32(p.constants[def.args[1]])
+		if d, ok := p.decorations[typeID]; ok && d.arrayStride > 0 {
+			return length * d.arrayStride
+		}
+		return length * p.sizeOf(def.args[0], matrixStride)
+	case opTypeStruct:
+		var end uint32
+		for i, memberType := range def.args {
+			var offset, stride uint32
+			if d, ok := p.members[typeID][uint32(i)]; ok {
+				offset, stride = d.offset, d.matrixStride
+			}
+			if size := offset + p.sizeOf(memberType, stride); size > end {
+				end = size
+			}
+		}
+		return end
+	}
+	return 0
+}
+
+// inputs collects location-decorated input variables of an entry point.
+func (p *parser) inputs(ep *entryPoint) []Input {
+	var inputs []Input
+	for _, id := range ep.interfaces {
+		var v *variable
+		for i := range p.variables {
+			if p.variables[i].id == id {
+				v = &p.variables[i]
+				break
+			}
+		}
+		if v == nil || v.storage != storageInput {
+			continue
+		}
+		d, ok := p.decorations[id]
+		if !ok || d.has[decorationBuiltIn] || !d.has[decorationLocation] {
+			continue
+		}
+		elem, count := p.unwrapArrays(p.pointee(v.typeID))
+		if ep.ExecutionModel != ExecutionModelVertex && ep.ExecutionModel != ExecutionModelFragment {
+			// per-vertex inputs of tessellation and geometry stages are arrayed
+			count = 1
+		}
+		format, columns := p.inputFormat(elem)
+		location := d.location
+		for i := uint32(0); i < count*columns; i++ {
+			inputs = append(inputs, Input{
+				Name:     p.names[id],
+				Location: location,
+				Format:   format,
+			})
+			location++
+			if format.Size() > 16 {
+				// 64-bit three and four component vectors take two locations
+				location++
+			}
+		}
+	}
+	sort.Slice(inputs, func(i, j int) bool {
+		return inputs[i].Location < inputs[j].Location
+	})
+	return inputs
+}
+
+// inputFormat gets the format of a single location of the type and the number of columns.
+func (p *parser) inputFormat(typeID uint32) (Format, uint32) {
+	def, ok := p.defs[typeID]
+	if !ok {
+		return FormatUndefined, 1
+	}
+	switch def.op {
+	case opTypeInt, opTypeFloat:
+		width, kind := scalar(def)
+		return makeFormat(width, kind, 1), 1
+	case opTypeVector:
+		if component, ok := p.defs[def.args[0]]; ok {
+			width, kind := scalar(component)
+			return makeFormat(width, kind, def.args[1]), 1
+		}
+	case opTypeMatrix:
+		column, _ := p.inputFormat(def.args[0])
+		return column, def.args[1]
+	}
+	return FormatUndefined, 1
+}
+
+// scalar gets the width and kind of a numeric scalar type, width is zero for other types.
+func scalar(def *instruction) (uint32, componentKind) {
+	switch def.op {
+	case opTypeInt:
+		if def.args[1] != 0 {
+			return def.args[0], kindSint
+		}
+		return def.args[0], kindUint
+	case opTypeFloat:
+		return def.args[0], kindFloat
+	}
+	return 0, kindUint
+}
+
+func (p *parser) specConstants() []SpecConstant {
+	var constants []SpecConstant
+	for _, id := range p.specIDs {
+		d, ok := p.decorations[id]
+		if !ok || !d.has[decorationSpecID] {
+			continue
+		}
+		c := SpecConstant{
+			ID:      d.specID,
+			Name:    p.names[id],
+			Size:    4,
+			Default: p.constants[id],
+		}
+		if def, ok := p.defs[id]; ok && def.op == opSpecConstant {
+			c.Size = p.sizeOf(def.args[0], 0)
+		}
+		constants = append(constants, c)
+	}
+	sort.Slice(constants, func(i, j int) bool {
+		return constants[i].ID < constants[j].ID
+	})
+	return constants
+}
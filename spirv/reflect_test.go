@@ -0,0 +1,287 @@
+package spirv
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+const (
+	storageOutput      = 3
+	decorationColMajor = 5
+	builtInPosition    = 0
+	dim2D              = 1
+	imageFormatUnknown = 0
+	sampledWithSampler = 1
+)
+
+// assembler builds SPIR-V modules word by word.
+type assembler struct {
+	words []uint32
+}
+
+func newAssembler() *assembler {
+	return &assembler{
+		words: []uint32{Magic, MakeVersion(1, 0), 0, 100, 0},
+	}
+}
+
+func (a *assembler) op(op uint32, args ...uint32) *assembler {
+	a.words = append(a.words, uint32(len(args)+1)<<16|op)
+	a.words = append(a.words, args...)
+	return a
+}
+
+func (a *assembler) name(id uint32, name string) *assembler {
+	return a.op(opName, append([]uint32{id}, str(name)...)...)
+}
+
+func (a *assembler) memberName(id, member uint32, name string) *assembler {
+	return a.op(opMemberName, append([]uint32{id, member}, str(name)...)...)
+}
+
+func (a *assembler) parse(t *testing.T) *Module {
+	t.Helper()
+	m, err := ParseWords(a.words)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// str encodes a nul-terminated string literal.
+func str(s string) []uint32 {
+	words := make([]uint32, len(s)/4+1)
+	for i := 0; i < len(s); i++ {
+		words[i/4] |= uint32(s[i]) << (8 * uint(i%4))
+	}
+	return words
+}
+
+func TestDescriptorBindings(t *testing.T) {
+	m := newAssembler().
+		name(9, "tex").
+		name(12, "ubo").
+		name(16, "data").
+		name(19, "textures").
+		op(opDecorate, 9, decorationDescriptorSet, 1).
+		op(opDecorate, 9, decorationBinding, 2).
+		op(opDecorate, 10, decorationBlock).
+		op(opMemberDecorate, 10, 0, decorationOffset, 0).
+		op(opDecorate, 12, decorationDescriptorSet, 0).
+		op(opDecorate, 12, decorationBinding, 0).
+		op(opDecorate, 14, decorationBlock).
+		op(opMemberDecorate, 14, 0, decorationOffset, 0).
+		op(opDecorate, 16, decorationDescriptorSet, 0).
+		op(opDecorate, 16, decorationBinding, 1).
+		op(opDecorate, 19, decorationDescriptorSet, 2).
+		op(opDecorate, 19, decorationBinding, 0).
+		op(opTypeFloat, 1, 32).
+		op(opTypeVector, 2, 1, 4).
+		op(opTypeImage, 3, 1, dim2D, 0, 0, 0, sampledWithSampler, imageFormatUnknown).
+		op(opTypeSampledImage, 4, 3).
+		op(opTypeInt, 5, 32, 0).
+		op(opConstant, 5, 6, 4).
+		op(opTypeArray, 7, 4, 6).
+		op(opTypePointer, 8, storageUniformConstant, 7).
+		op(opVariable, 8, 9, storageUniformConstant).
+		op(opTypeStruct, 10, 2).
+		op(opTypePointer, 11, storageUniform, 10).
+		op(opVariable, 11, 12, storageUniform).
+		op(opTypeRuntimeArray, 13, 1).
+		op(opTypeStruct, 14, 13).
+		op(opTypePointer, 15, storageStorageBuffer, 14).
+		op(opVariable, 15, 16, storageStorageBuffer).
+		op(opTypeRuntimeArray, 17, 4).
+		op(opTypePointer, 18, storageUniformConstant, 17).
+		op(opVariable, 18, 19, storageUniformConstant).
+		parse(t)
+
+	want := []DescriptorBinding{
+		{Name: "ubo", Set: 0, Binding: 0, Type: DescriptorTypeUniformBuffer, Count: 1},
+		{Name: "data", Set: 0, Binding: 1, Type: DescriptorTypeStorageBuffer, Count: 1},
+		{Name: "tex", Set: 1, Binding: 2, Type: DescriptorTypeCombinedImageSampler, Count: 4},
+		{Name: "textures", Set: 2, Binding: 0, Type: DescriptorTypeCombinedImageSampler, Count: 0},
+	}
+	if !reflect.DeepEqual(m.DescriptorBindings, want) {
+		t.Errorf("got bindings %+v, want %+v", m.DescriptorBindings, want)
+	}
+}
+
+func TestPushConstants(t *testing.T) {
+	m := newAssembler().
+		name(9, "pc").
+		memberName(7, 0, "color").
+		memberName(7, 1, "transform").
+		memberName(7, 2, "weights").
+		op(opDecorate, 6, decorationArrayStride, 16).
+		op(opDecorate, 7, decorationBlock).
+		op(opMemberDecorate, 7, 0, decorationOffset, 16).
+		op(opMemberDecorate, 7, 1, decorationColMajor).
+		op(opMemberDecorate, 7, 1, decorationOffset, 32).
+		op(opMemberDecorate, 7, 1, decorationMatrixStride, 16).
+		op(opMemberDecorate, 7, 2, decorationOffset, 96).
+		op(opTypeFloat, 1, 32).
+		op(opTypeVector, 2, 1, 4).
+		op(opTypeMatrix, 3, 2, 4).
+		op(opTypeInt, 4, 32, 0).
+		op(opConstant, 4, 5, 2).
+		op(opTypeArray, 6, 1, 5).
+		op(opTypeStruct, 7, 2, 3, 6).
+		op(opTypePointer, 8, storagePushConstant, 7).
+		op(opVariable, 8, 9, storagePushConstant).
+		parse(t)
+
+	want := []Block{{
+		Name:   "pc",
+		Offset: 16,
+		Size:   112,
+		Members: []BlockMember{
+			{Name: "color", Offset: 16, Size: 16},
+			{Name: "transform", Offset: 32, Size: 64},
+			{Name: "weights", Offset: 96, Size: 32},
+		},
+	}}
+	if !reflect.DeepEqual(m.PushConstants, want) {
+		t.Errorf("got push constants %+v, want %+v", m.PushConstants, want)
+	}
+}
+
+func TestStageInputs(t *testing.T) {
+	m := newAssembler().
+		op(opEntryPoint, append(append([]uint32{uint32(ExecutionModelVertex), 20}, str("main")...),
+			11, 12, 13, 14, 15, 16)...).
+		name(11, "position").
+		name(12, "model").
+		name(13, "ids").
+		name(14, "precise").
+		name(15, "gl_Position").
+		name(16, "color").
+		op(opDecorate, 11, decorationLocation, 0).
+		op(opDecorate, 12, decorationLocation, 1).
+		op(opDecorate, 13, decorationLocation, 3).
+		op(opDecorate, 14, decorationLocation, 5).
+		op(opDecorate, 15, decorationBuiltIn, builtInPosition).
+		op(opDecorate, 16, decorationLocation, 0).
+		op(opTypeFloat, 1, 32).
+		op(opTypeVector, 2, 1, 3).
+		op(opTypeVector, 3, 1, 2).
+		op(opTypeMatrix, 4, 3, 2).
+		op(opTypeInt, 5, 32, 1).
+		op(opTypeVector, 6, 5, 4).
+		op(opTypeInt, 7, 32, 0).
+		op(opConstant, 7, 8, 2).
+		op(opTypeArray, 9, 6, 8).
+		op(opTypeFloat, 10, 64).
+		op(opTypeVector, 17, 10, 4).
+		op(opTypeVector, 18, 1, 4).
+		op(opTypePointer, 21, storageInput, 2).
+		op(opTypePointer, 22, storageInput, 4).
+		op(opTypePointer, 23, storageInput, 9).
+		op(opTypePointer, 24, storageInput, 17).
+		op(opTypePointer, 25, storageInput, 18).
+		op(opTypePointer, 26, storageOutput, 18).
+		op(opVariable, 21, 11, storageInput).
+		op(opVariable, 22, 12, storageInput).
+		op(opVariable, 23, 13, storageInput).
+		op(opVariable, 24, 14, storageInput).
+		op(opVariable, 25, 15, storageInput).
+		op(opVariable, 26, 16, storageOutput).
+		parse(t)
+
+	if len(m.EntryPoints) != 1 {
+		t.Fatalf("got %d entry points, want 1", len(m.EntryPoints))
+	}
+	ep := m.EntryPoints[0]
+	if ep.Name != "main" || ep.ExecutionModel != ExecutionModelVertex {
+		t.Errorf("got entry point %q %v, want main Vertex", ep.Name, ep.ExecutionModel)
+	}
+	// built-ins and outputs are not reported
+	want := []Input{
+		{Name: "position", Location: 0, Format: FormatR32g32b32Sfloat},
+		{Name: "model", Location: 1, Format: FormatR32g32Sfloat},
+		{Name: "model", Location: 2, Format: FormatR32g32Sfloat},
+		{Name: "ids", Location: 3, Format: FormatR32g32b32a32Sint},
+		{Name: "ids", Location: 4, Format: FormatR32g32b32a32Sint},
+		{Name: "precise", Location: 5, Format: FormatR64g64b64a64Sfloat},
+	}
+	if !reflect.DeepEqual(ep.Inputs, want) {
+		t.Errorf("got inputs %+v, want %+v", ep.Inputs, want)
+	}
+}
+
+func TestParseByteOrder(t *testing.T) {
+	words := newAssembler().op(opTypeFloat, 1, 32).words
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		data := make([]byte, len(words)*4)
+		for i, w := range words {
+			order.PutUint32(data[i*4:], w)
+		}
+		m, err := Parse(data)
+		if err != nil {
+			t.Errorf("%v: %v", order, err)
+			continue
+		}
+		if m.Header.VersionMajor() != 1 || m.Header.VersionMinor() != 0 {
+			t.Errorf("%v: got version %d.%d, want 1.0", order, m.Header.VersionMajor(), m.Header.VersionMinor())
+		}
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	header := newAssembler().words
+	tests := []struct {
+		name  string
+		words []uint32
+	}{
+		{"truncated header", header[:3]},
+		{"bad magic", append([]uint32{0xdeadbeef}, header[1:]...)},
+		{"bad version", append([]uint32{Magic, MakeVersion(2, 0)}, header[2:]...)},
+		{"zero bound", append(append([]uint32{}, header[:3]...), 0, 0)},
+		{"zero word count", append(append([]uint32{}, header...), opTypeFloat)},
+		{"instruction past the end", append(append([]uint32{}, header...), 3<<16|opTypeFloat, 1)},
+		{"missing type operands", newAssembler().op(opTypeVector, 2, 1).words},
+		{"missing entry point operands", newAssembler().op(opEntryPoint, 0, 1).words},
+		{"array of itself", newAssembler().
+			op(opTypeInt, 1, 32, 0).
+			op(opConstant, 1, 2, 4).
+			op(opTypeArray, 3, 3, 2).
+			words},
+		{"runtime array of itself", newAssembler().op(opTypeRuntimeArray, 1, 1).words},
+		{"struct of itself", newAssembler().
+			op(opTypeFloat, 1, 32).
+			op(opTypeStruct, 2, 1, 2).
+			words},
+		{"struct through an array", newAssembler().
+			op(opTypeInt, 1, 32, 0).
+			op(opConstant, 1, 2, 4).
+			op(opTypeStruct, 3, 4).
+			op(opTypeArray, 4, 3, 2).
+			op(opTypePointer, 5, storagePushConstant, 3).
+			op(opVariable, 5, 6, storagePushConstant).
+			words},
+	}
+	for _, test := range tests {
+		if _, err := ParseWords(test.words); err == nil {
+			t.Errorf("%s: got no error", test.name)
+		}
+	}
+	if _, err := Words([]byte{1, 2, 3}); err == nil {
+		t.Error("unaligned size: got no error")
+	}
+	if _, err := Words(make([]byte, HeaderWords*4)); err != ErrMagic {
+		t.Errorf("zero magic: got %v, want %v", err, ErrMagic)
+	}
+}
+
+func TestSelfReferenceThroughPointer(t *testing.T) {
+	// a linked list node refers to itself through a pointer, which is valid
+	m := newAssembler().
+		op(opTypeInt, 1, 32, 0).
+		op(opTypeStruct, 2, 1, 3).
+		op(opTypePointer, 3, storageStorageBuffer, 2).
+		parse(t)
+	if len(m.DescriptorBindings) != 0 {
+		t.Errorf("got bindings %+v, want none", m.DescriptorBindings)
+	}
+}
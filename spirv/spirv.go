@@ -0,0 +1,276 @@
+// Package spirv implements a minimal SPIR-V binary parser that extracts the
+// reflection data needed to build Vulkan pipelines: entry points, workgroup
+// sizes, descriptor bindings, push-constant blocks, stage inputs and
+// specialization constants. It has no cgo or Vulkan dependencies.
+package spirv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Magic is the SPIR-V magic number as it appears in the first word of a module.
+const Magic = 0x07230203
+
+// HeaderWords is the number of words in the SPIR-V module header.
+const HeaderWords = 5
+
+// Header describes the SPIR-V module header.
+type Header struct {
+	// Magic must be equal to Magic.
+	Magic uint32
+	// Version is the SPIR-V version number, 0x00MMmm00 for version MM.mm.
+	Version uint32
+	// Generator is the magic number of the tool that generated the module.
+	Generator uint32
+	// Bound is the upper bound of all IDs used in the module.
+	Bound uint32
+	// Schema is reserved and must be zero.
+	Schema uint32
+}
+
+// MakeVersion returns the SPIR-V encoding of the version major.minor.
+func MakeVersion(major, minor int) uint32 {
+	return uint32(major)<<16 | uint32(minor)<<8
+}
+
+// VersionMajor gets the major SPIR-V version of the module.
+func (h *Header) VersionMajor() int {
+	return int(h.Version>>16) & 0xff
+}
+
+// VersionMinor gets the minor SPIR-V version of the module.
+func (h *Header) VersionMinor() int {
+	return int(h.Version>>8) & 0xff
+}
+
+func (h *Header) String() string {
+	return fmt.Sprintf("SPIR-V %d.%d (generator 0x%08x, bound %d)",
+		h.VersionMajor(), h.VersionMinor(), h.Generator, h.Bound)
+}
+
+var (
+	ErrTruncated = errors.New("spirv: module is truncated")
+	ErrMagic     = errors.New("spirv: invalid magic number")
+)
+
+// Words converts a SPIR-V binary into a freshly allocated slice of words in host order.
+// Modules written with the opposite endianness are byte-swapped, so the returned words
+// always start with Magic. The input does not need to be aligned.
+func Words(data []byte) ([]uint32, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("spirv: module size %d is not a multiple of 4", len(data))
+	}
+	if len(data) < HeaderWords*4 {
+		return nil, ErrTruncated
+	}
+	var order binary.ByteOrder
+	switch {
+	case binary.LittleEndian.Uint32(data) == Magic:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(data) == Magic:
+		order = binary.BigEndian
+	default:
+		return nil, ErrMagic
+	}
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = order.Uint32(data[i*4:])
+	}
+	return words, nil
+}
+
+// ParseHeader validates and reads the header of a module given in host-order words.
+func ParseHeader(words []uint32) (*Header, error) {
+	if len(words) < HeaderWords {
+		return nil, ErrTruncated
+	}
+	h := &Header{
+		Magic:     words[0],
+		Version:   words[1],
+		Generator: words[2],
+		Bound:     words[3],
+		Schema:    words[4],
+	}
+	if h.Magic != Magic {
+		return nil, ErrMagic
+	}
+	if h.Version&0xff0000ff != 0 || h.VersionMajor() != 1 {
+		return nil, fmt.Errorf("spirv: unsupported version 0x%08x", h.Version)
+	}
+	if h.Bound == 0 {
+		return nil, errors.New("spirv: ID bound must be greater than zero")
+	}
+	return h, nil
+}
+
+// ExecutionModel is the shader stage of an entry point.
+type ExecutionModel uint32
+
+const (
+	ExecutionModelVertex                 ExecutionModel = 0
+	ExecutionModelTessellationControl    ExecutionModel = 1
+	ExecutionModelTessellationEvaluation ExecutionModel = 2
+	ExecutionModelGeometry               ExecutionModel = 3
+	ExecutionModelFragment               ExecutionModel = 4
+	ExecutionModelGLCompute              ExecutionModel = 5
+	ExecutionModelKernel                 ExecutionModel = 6
+)
+
+func (m ExecutionModel) String() string {
+	switch m {
+	case ExecutionModelVertex:
+		return "Vertex"
+	case ExecutionModelTessellationControl:
+		return "TessellationControl"
+	case ExecutionModelTessellationEvaluation:
+		return "TessellationEvaluation"
+	case ExecutionModelGeometry:
+		return "Geometry"
+	case ExecutionModelFragment:
+		return "Fragment"
+	case ExecutionModelGLCompute:
+		return "GLCompute"
+	case ExecutionModelKernel:
+		return "Kernel"
+	default:
+		return fmt.Sprintf("ExecutionModel(%d)", uint32(m))
+	}
+}
+
+// DescriptorType is the kind of a descriptor binding, its values match VkDescriptorType.
+type DescriptorType uint32
+
+const (
+	DescriptorTypeSampler                  DescriptorType = 0
+	DescriptorTypeCombinedImageSampler     DescriptorType = 1
+	DescriptorTypeSampledImage             DescriptorType = 2
+	DescriptorTypeStorageImage             DescriptorType = 3
+	DescriptorTypeUniformTexelBuffer       DescriptorType = 4
+	DescriptorTypeStorageTexelBuffer       DescriptorType = 5
+	DescriptorTypeUniformBuffer            DescriptorType = 6
+	DescriptorTypeStorageBuffer            DescriptorType = 7
+	DescriptorTypeInputAttachment          DescriptorType = 10
+	DescriptorTypeAccelerationStructureKHR DescriptorType = 1000150000
+)
+
+func (t DescriptorType) String() string {
+	switch t {
+	case DescriptorTypeSampler:
+		return "Sampler"
+	case DescriptorTypeCombinedImageSampler:
+		return "CombinedImageSampler"
+	case DescriptorTypeSampledImage:
+		return "SampledImage"
+	case DescriptorTypeStorageImage:
+		return "StorageImage"
+	case DescriptorTypeUniformTexelBuffer:
+		return "UniformTexelBuffer"
+	case DescriptorTypeStorageTexelBuffer:
+		return "StorageTexelBuffer"
+	case DescriptorTypeUniformBuffer:
+		return "UniformBuffer"
+	case DescriptorTypeStorageBuffer:
+		return "StorageBuffer"
+	case DescriptorTypeInputAttachment:
+		return "InputAttachment"
+	case DescriptorTypeAccelerationStructureKHR:
+		return "AccelerationStructure"
+	default:
+		return fmt.Sprintf("DescriptorType(%d)", uint32(t))
+	}
+}
+
+// Format is the data format of a stage input, its values match VkFormat.
+type Format uint32
+
+const (
+	FormatUndefined          Format = 0
+	FormatR16Uint            Format = 74
+	FormatR16Sint            Format = 75
+	FormatR16Sfloat          Format = 76
+	FormatR16g16Uint         Format = 81
+	FormatR16g16Sint         Format = 82
+	FormatR16g16Sfloat       Format = 83
+	FormatR16g16b16Uint      Format = 88
+	FormatR16g16b16Sint      Format = 89
+	FormatR16g16b16Sfloat    Format = 90
+	FormatR16g16b16a16Uint   Format = 95
+	FormatR16g16b16a16Sint   Format = 96
+	FormatR16g16b16a16Sfloat Format = 97
+	FormatR32Uint            Format = 98
+	FormatR32Sint            Format = 99
+	FormatR32Sfloat          Format = 100
+	FormatR32g32Uint         Format = 101
+	FormatR32g32Sint         Format = 102
+	FormatR32g32Sfloat       Format = 103
+	FormatR32g32b32Uint      Format = 104
+	FormatR32g32b32Sint      Format = 105
+	FormatR32g32b32Sfloat    Format = 106
+	FormatR32g32b32a32Uint   Format = 107
+	FormatR32g32b32a32Sint   Format = 108
+	FormatR32g32b32a32Sfloat Format = 109
+	FormatR64Uint            Format = 110
+	FormatR64Sint            Format = 111
+	FormatR64Sfloat          Format = 112
+	FormatR64g64Uint         Format = 113
+	FormatR64g64Sint         Format = 114
+	FormatR64g64Sfloat       Format = 115
+	FormatR64g64b64Uint      Format = 116
+	FormatR64g64b64Sint      Format = 117
+	FormatR64g64b64Sfloat    Format = 118
+	FormatR64g64b64a64Uint   Format = 119
+	FormatR64g64b64a64Sint   Format = 120
+	FormatR64g64b64a64Sfloat Format = 121
+)
+
+// componentKind distinguishes the numeric kinds of a format component.
+type componentKind int
+
+const (
+	kindUint componentKind = iota
+	kindSint
+	kindFloat
+)
+
+// formatTable maps component width, kind and count (1-4) to a Format.
+var formatTable = map[uint32][3][4]Format{
+	16: {
+		kindUint:  {FormatR16Uint, FormatR16g16Uint, FormatR16g16b16Uint, FormatR16g16b16a16Uint},
+		kindSint:  {FormatR16Sint, FormatR16g16Sint, FormatR16g16b16Sint, FormatR16g16b16a16Sint},
+		kindFloat: {FormatR16Sfloat, FormatR16g16Sfloat, FormatR16g16b16Sfloat, FormatR16g16b16a16Sfloat},
+	},
+	32: {
+		kindUint:  {FormatR32Uint, FormatR32g32Uint, FormatR32g32b32Uint, FormatR32g32b32a32Uint},
+		kindSint:  {FormatR32Sint, FormatR32g32Sint, FormatR32g32b32Sint, FormatR32g32b32a32Sint},
+		kindFloat: {FormatR32Sfloat, FormatR32g32Sfloat, FormatR32g32b32Sfloat, FormatR32g32b32a32Sfloat},
+	},
+	64: {
+		kindUint:  {FormatR64Uint, FormatR64g64Uint, FormatR64g64b64Uint, FormatR64g64b64a64Uint},
+		kindSint:  {FormatR64Sint, FormatR64g64Sint, FormatR64g64b64Sint, FormatR64g64b64a64Sint},
+		kindFloat: {FormatR64Sfloat, FormatR64g64Sfloat, FormatR64g64b64Sfloat, FormatR64g64b64a64Sfloat},
+	},
+}
+
+func makeFormat(width uint32, kind componentKind, count uint32) Format {
+	table, ok := formatTable[width]
+	if !ok || count < 1 || count > 4 {
+		return FormatUndefined
+	}
+	return table[kind][count-1]
+}
+
+// Size gets the size of a single element of the format in bytes, or zero if unknown.
+func (f Format) Size() uint32 {
+	for width, kinds := range formatTable {
+		for _, counts := range kinds {
+			for i, format := range counts {
+				if format == f {
+					return width / 8 * uint32(i+1)
+				}
+			}
+		}
+	}
+	return 0
+}
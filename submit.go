@@ -0,0 +1,429 @@
+package asche
+
+import (
+	gocontext "context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// commandSet is a command pool with a single command buffer and a fence,
+// used by one submission at a time so submissions don't need to be synchronized.
+type commandSet struct {
+	family uint32
+	pool   vk.CommandPool
+	cmd    vk.CommandBuffer
+	fence  vk.Fence
+}
+
+// commandSets pools the command sets of one-shot submissions, the free sets are kept per queue family.
+type commandSets struct {
+	mu   sync.Mutex
+	free map[uint32][]*commandSet
+	all  []*commandSet
+}
+
+func (p *commandSets) get(device vk.Device, family uint32) *commandSet {
+	p.mu.Lock()
+	if free := p.free[family]; len(free) > 0 {
+		set := free[len(free)-1]
+		p.free[family] = free[:len(free)-1]
+		p.mu.Unlock()
+		return set
+	}
+	p.mu.Unlock()
+
+	d := deviceDriver(device)
+	set := &commandSet{
+		family: family,
+	}
+	ret := d.CreateCommandPool(device, &vk.CommandPoolCreateInfo{
+		SType:            vk.StructureTypeCommandPoolCreateInfo,
+		Flags:            vk.CommandPoolCreateFlags(vk.CommandPoolCreateTransientBit),
+		QueueFamilyIndex: family,
+	}, nil, &set.pool)
+	orPanic(NewError(ret))
+	cmd := make([]vk.CommandBuffer, 1)
+	ret = d.AllocateCommandBuffers(device, &vk.CommandBufferAllocateInfo{
+		SType:              vk.StructureTypeCommandBufferAllocateInfo,
+		CommandPool:        set.pool,
+		Level:              vk.CommandBufferLevelPrimary,
+		CommandBufferCount: 1,
+	}, cmd)
+	orPanic(NewError(ret), func() {
+		d.DestroyCommandPool(device, set.pool, nil)
+	})
+	set.cmd = cmd[0]
+	ret = d.CreateFence(device, &vk.FenceCreateInfo{
+		SType: vk.StructureTypeFenceCreateInfo,
+	}, nil, &set.fence)
+	orPanic(NewError(ret), func() {
+		d.DestroyCommandPool(device, set.pool, nil)
+	})
+
+	p.mu.Lock()
+	p.all = append(p.all, set)
+	p.mu.Unlock()
+	return set
+}
+
+// put resets the command set and returns it to the pool, the submission must be complete.
+func (p *commandSets) put(device vk.Device, set *commandSet) {
+	d := deviceDriver(device)
+	d.ResetCommandPool(device, set.pool, 0)
+	d.ResetFences(device, 1, []vk.Fence{set.fence})
+	p.mu.Lock()
+	if p.free == nil {
+		p.free = make(map[uint32][]*commandSet)
+	}
+	p.free[set.family] = append(p.free[set.family], set)
+	p.mu.Unlock()
+}
+
+// destroy destroys all the command sets, the device must be idle.
+func (p *commandSets) destroy(device vk.Device) {
+	d := deviceDriver(device)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, set := range p.all {
+		d.DestroyFence(device, set.fence, nil)
+		d.DestroyCommandPool(device, set.pool, nil)
+	}
+	p.free = nil
+	p.all = nil
+}
+
+// Submission is a one-shot submission started with SubmitAsync.
+type Submission struct {
+	device    vk.Device
+	sets      *commandSets
+	set       *commandSet
+	deletions *deletionQueue
+	serial    uint64
+
+	done chan struct{}
+	err  error
+}
+
+// Done gets a channel that is closed once the submission has completed.
+func (s *Submission) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until the submission has completed or ctx is done, in which case ctx.Err() is returned.
+// It can be called more than once and from multiple goroutines.
+func (s *Submission) Wait(ctx gocontext.Context) error {
+	select {
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Submission) finish(err error) {
+	s.err = err
+	s.sets.put(s.device, s.set)
+	s.set = nil
+	// the deferred destroy functions run on the frame thread
+	s.deletions.retire(s.serial)
+	close(s.done)
+}
+
+// fail completes the submission with err without knowing whether its work is done.
+// The command set may be in use still, so it's only destroyed along with the pool,
+// and the serial stays in flight, holding the deferred destroys until the device is idle.
+func (s *Submission) fail(err error) {
+	s.err = err
+	s.set = nil
+	close(s.done)
+}
+
+// errSubmissionAbandoned fails the submissions added to a destroyed fence waiter.
+var errSubmissionAbandoned = errors.New("vulkan error: submission made while the context is destroyed")
+
+// fenceWaitTimeout bounds a single wait of the fence waiter, it only matters for submissions
+// added while the wake fence of the wait is used already, see fenceWaiter.add.
+const fenceWaitTimeout = uint64(time.Second)
+
+// fenceWaiter waits on fences of pending submissions in a background goroutine
+// and completes the submissions as soon as their fences are signaled.
+type fenceWaiter struct {
+	vkd    driver.Driver
+	device vk.Device
+
+	mu      sync.Mutex
+	stopped bool
+	pending []*Submission
+	// armed is waited on along with the pending fences, the first submission added
+	// during the wait signals it through an empty submission to wake the waiter.
+	armed vk.Fence
+	// fired are the wake fences submitted and not reset yet.
+	fired []vk.Fence
+	free  []vk.Fence
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newFenceWaiter(device vk.Device) *fenceWaiter {
+	w := &fenceWaiter{
+		vkd:    deviceDriver(device),
+		device: device,
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// add makes the waiter wait on the submission made to q,
+// the submission fails if the waiter is destroyed already.
+func (w *fenceWaiter) add(s *Submission, q *Queue) {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		s.fail(errSubmissionAbandoned)
+		return
+	}
+	w.pending = append(w.pending, s)
+	fence := w.armed
+	if fence != vk.NullFence {
+		w.armed = vk.NullFence
+		w.fired = append(w.fired, fence)
+	}
+	w.mu.Unlock()
+	if fence != vk.NullFence {
+		// signaled once the queue reaches it, i.e. along with the submission;
+		// it fails only if the device is lost, which fails the wait anyway
+		q.Submit(nil, fence)
+	}
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *fenceWaiter) run() {
+	defer close(w.done)
+	for {
+		w.recycle()
+		w.mu.Lock()
+		pending := make([]*Submission, len(w.pending))
+		copy(pending, w.pending)
+		fences := make([]vk.Fence, 0, len(pending)+len(w.fired)+1)
+		for _, s := range pending {
+			fences = append(fences, s.set.fence)
+		}
+		if len(pending) > 0 {
+			if w.armed == vk.NullFence {
+				w.armed = w.wakeFence()
+			}
+			if w.armed != vk.NullFence {
+				fences = append(fences, w.armed)
+			}
+			fences = append(fences, w.fired...)
+		}
+		w.mu.Unlock()
+
+		if len(pending) == 0 {
+			select {
+			case <-w.wake:
+				continue
+			case <-w.stop:
+				return
+			}
+		}
+		select {
+		case <-w.stop:
+			// the device is idle
+			w.complete(pending)
+			return
+		default:
+		}
+		ret := w.vkd.WaitForFences(w.device, uint32(len(fences)), fences, vk.False, fenceWaitTimeout)
+		switch ret {
+		case vk.Timeout:
+		case vk.Success:
+			w.complete(pending)
+		default:
+			// the fences can't be relied upon anymore
+			w.fail(pending, NewError(ret))
+		}
+	}
+}
+
+// wakeFence gets an unsignaled fence, it's null if none can be created
+// and the waiter falls back to the timeout then. It must be called with mu held.
+func (w *fenceWaiter) wakeFence() vk.Fence {
+	if n := len(w.free); n > 0 {
+		fence := w.free[n-1]
+		w.free = w.free[:n-1]
+		return fence
+	}
+	var fence vk.Fence
+	ret := w.vkd.CreateFence(w.device, &vk.FenceCreateInfo{
+		SType: vk.StructureTypeFenceCreateInfo,
+	}, nil, &fence)
+	if isError(ret) {
+		return vk.NullFence
+	}
+	return fence
+}
+
+// recycle resets the wake fences that have been signaled.
+func (w *fenceWaiter) recycle() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	n := 0
+	for _, fence := range w.fired {
+		if w.vkd.GetFenceStatus(w.device, fence) == vk.Success {
+			w.vkd.ResetFences(w.device, 1, []vk.Fence{fence})
+			w.free = append(w.free, fence)
+			continue
+		}
+		w.fired[n] = fence
+		n++
+	}
+	w.fired = w.fired[:n]
+}
+
+// complete finishes the submissions whose fences are no longer unsignaled.
+func (w *fenceWaiter) complete(pending []*Submission) {
+	finished := make(map[*Submission]bool)
+	for _, s := range pending {
+		ret := w.vkd.GetFenceStatus(w.device, s.set.fence)
+		if ret == vk.NotReady {
+			continue
+		}
+		finished[s] = true
+		s.finish(NewError(ret))
+	}
+	w.remove(finished)
+}
+
+// fail fails the submissions with err.
+func (w *fenceWaiter) fail(pending []*Submission, err error) {
+	finished := make(map[*Submission]bool)
+	for _, s := range pending {
+		finished[s] = true
+		s.fail(err)
+	}
+	w.remove(finished)
+}
+
+func (w *fenceWaiter) remove(finished map[*Submission]bool) {
+	w.mu.Lock()
+	n := 0
+	for _, s := range w.pending {
+		if !finished[s] {
+			w.pending[n] = s
+			n++
+		}
+	}
+	w.pending = w.pending[:n]
+	w.mu.Unlock()
+}
+
+// destroy stops the waiter, the device must be idle so all the submissions complete.
+// Submissions added from now on fail.
+func (w *fenceWaiter) destroy() {
+	w.mu.Lock()
+	w.stopped = true
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	// the ones added during the last wait
+	w.complete(append([]*Submission(nil), w.pending...))
+	w.fail(append([]*Submission(nil), w.pending...), errSubmissionAbandoned)
+	fences := append(w.free, w.fired...)
+	if w.armed != vk.NullFence {
+		fences = append(fences, w.armed)
+	}
+	for _, fence := range fences {
+		w.vkd.DestroyFence(w.device, fence, nil)
+	}
+	w.armed, w.fired, w.free = vk.NullFence, nil, nil
+}
+
+// syncQueue finds the serialized queue of a platform queue.
+func (c *context) syncQueue(queue vk.Queue) (*Queue, bool) {
+	switch queue {
+	case c.platform.GraphicsQueue():
+		return c.platform.SyncGraphicsQueue(), true
+	case c.platform.PresentQueue():
+		return c.platform.SyncPresentQueue(), true
+	default:
+		return nil, false
+	}
+}
+
+func (c *context) Submit(queue vk.Queue, record func(cmd vk.CommandBuffer) error) (err error) {
+	s, err := c.SubmitAsync(queue, record)
+	if err != nil {
+		return err
+	}
+	defer c.checkValidation(&err)
+	return s.Wait(gocontext.Background())
+}
+
+func (c *context) SubmitAsync(queue vk.Queue, record func(cmd vk.CommandBuffer) error) (s *Submission, err error) {
+	defer c.checkValidation(&err)
+	defer checkErr(&err)
+	q, ok := c.syncQueue(queue)
+	if !ok {
+		return nil, errors.New("vulkan error: queue doesn't belong to the platform")
+	}
+	set := c.commandSets.get(c.device, q.Family())
+	// the set goes back to the pool unless submitted, even if record panics,
+	// resetting the pool resets the command buffer left in the recording state
+	var submitted bool
+	defer func() {
+		if !submitted {
+			c.commandSets.put(c.device, set)
+		}
+	}()
+
+	ret := c.vkd.BeginCommandBuffer(set.cmd, &vk.CommandBufferBeginInfo{
+		SType: vk.StructureTypeCommandBufferBeginInfo,
+		Flags: vk.CommandBufferUsageFlags(vk.CommandBufferUsageOneTimeSubmitBit),
+	})
+	orPanic(NewError(ret))
+	if err := record(set.cmd); err != nil {
+		c.vkd.EndCommandBuffer(set.cmd)
+		return nil, err
+	}
+	ret = c.vkd.EndCommandBuffer(set.cmd)
+	orPanic(NewError(ret))
+
+	// objects deferred from now on wait for the submission
+	serial := c.deletions.submit()
+	err = q.Submit([]vk.SubmitInfo{{
+		SType:              vk.StructureTypeSubmitInfo,
+		CommandBufferCount: 1,
+		PCommandBuffers:    []vk.CommandBuffer{set.cmd},
+	}}, set.fence)
+	orPanic(err, func() {
+		c.deletions.retire(serial)
+	})
+	submitted = true
+
+	s = &Submission{
+		device:    c.device,
+		sets:      &c.commandSets,
+		set:       set,
+		deletions: &c.deletions,
+		serial:    serial,
+		done:      make(chan struct{}),
+	}
+	c.fenceWaiterOnce.Do(func() {
+		c.fenceWaiter = newFenceWaiter(c.device)
+	})
+	c.fenceWaiter.add(s, q)
+	return s, nil
+}
@@ -0,0 +1,210 @@
+package asche
+
+import (
+	gocontext "context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestSubmit(t *testing.T) {
+	errRecord := errors.New("record failed")
+	tests := []struct {
+		name    string
+		record  func(cmd vk.CommandBuffer) error
+		fail    string
+		foreign bool
+		want    string
+		is      error
+	}{
+		{
+			name:   "ok",
+			record: func(cmd vk.CommandBuffer) error { return nil },
+		},
+		{
+			name:   "record error",
+			record: func(cmd vk.CommandBuffer) error { return errRecord },
+			want:   "record failed",
+			is:     errRecord,
+		},
+		{
+			name:   "record panic",
+			record: func(cmd vk.CommandBuffer) error { panic("boom") },
+			want:   "boom",
+		},
+		{
+			name:   "begin failure",
+			record: func(cmd vk.CommandBuffer) error { return nil },
+			fail:   "BeginCommandBuffer",
+			want:   "vulkan error",
+		},
+		{
+			name:   "submit failure",
+			record: func(cmd vk.CommandBuffer) error { return nil },
+			fail:   "QueueSubmit",
+			want:   "vulkan error",
+		},
+		{
+			name:    "foreign queue",
+			record:  func(cmd vk.CommandBuffer) error { return nil },
+			foreign: true,
+			want:    "queue doesn't belong to the platform",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			_, p := newFakePlatform(t, app)
+			ctx := app.Context()
+			pools := countCalls(app.fd, "CreateCommandPool")
+			submits := countCalls(app.fd, "QueueSubmit")
+
+			queue := p.GraphicsQueue()
+			if tt.foreign {
+				queue = nil
+			}
+			if tt.fail != "" {
+				app.fd.Fail = func(call string) vk.Result {
+					if call == tt.fail {
+						return vk.ErrorOutOfDeviceMemory
+					}
+					return vk.Success
+				}
+			}
+			err := ctx.Submit(queue, tt.record)
+			app.fd.Fail = nil
+			if tt.want == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if n := countCalls(app.fd, "QueueSubmit") - submits; n != 1 {
+					t.Errorf("%d submissions, want 1", n)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want %q", err, tt.want)
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Error("record error not returned as is")
+			}
+
+			// the command set is back in the pool
+			if err := ctx.Submit(p.GraphicsQueue(), func(cmd vk.CommandBuffer) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+			if n := countCalls(app.fd, "CreateCommandPool") - pools; n != 1 {
+				t.Errorf("%d command pools created, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSubmitAsync(t *testing.T) {
+	app := &fakeApp{fd: fake.New()}
+	_, p := newFakePlatform(t, app)
+	ctx := app.Context()
+
+	const n = 16
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := ctx.SubmitAsync(p.GraphicsQueue(), func(cmd vk.CommandBuffer) error { return nil })
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			select {
+			case <-s.Done():
+			case <-time.After(5 * time.Second):
+				errs[i] = errors.New("submission not done")
+				return
+			}
+			errs[i] = s.Wait(gocontext.Background())
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFenceWaiterFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     vk.Result
+		deviceLost bool
+	}{
+		{name: "out of host memory", result: vk.ErrorOutOfHostMemory},
+		{name: "device lost", result: vk.ErrorDeviceLost, deviceLost: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &fakeApp{fd: fake.New()}
+			_, p := newFakePlatform(t, app)
+			ctx := app.Context()
+
+			var mu sync.Mutex
+			waits := 0
+			app.fd.Fail = func(call string) vk.Result {
+				if call == "WaitForFences" {
+					mu.Lock()
+					waits++
+					mu.Unlock()
+					return tt.result
+				}
+				return vk.Success
+			}
+			s, err := ctx.SubmitAsync(p.GraphicsQueue(), func(cmd vk.CommandBuffer) error { return nil })
+			if err != nil {
+				t.Fatal(err)
+			}
+			wctx, cancel := gocontext.WithTimeout(gocontext.Background(), 5*time.Second)
+			defer cancel()
+			err = s.Wait(wctx)
+			if err == nil || errors.Is(err, gocontext.DeadlineExceeded) {
+				t.Fatalf("got %v, want the wait error", err)
+			}
+			if got := errors.Is(err, ErrDeviceLost); got != tt.deviceLost {
+				t.Errorf("errors.Is(err, ErrDeviceLost) = %v, want %v", got, tt.deviceLost)
+			}
+
+			// the waiter idles instead of waiting again
+			mu.Lock()
+			before := waits
+			mu.Unlock()
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			after := waits
+			mu.Unlock()
+			if after != before {
+				t.Errorf("%d more waits after the submission has failed", after-before)
+			}
+			app.fd.Fail = nil
+		})
+	}
+}
+
+func TestFenceWaiterDestroyed(t *testing.T) {
+	app := &fakeApp{fd: fake.New()}
+	_, p := newFakePlatform(t, app)
+
+	w := newFenceWaiter(p.Device())
+	w.destroy()
+	s := &Submission{done: make(chan struct{})}
+	w.add(s, p.SyncGraphicsQueue())
+	select {
+	case <-s.Done():
+	default:
+		t.Fatal("submission added to a destroyed waiter is not done")
+	}
+	if err := s.Wait(gocontext.Background()); !errors.Is(err, errSubmissionAbandoned) {
+		t.Fatalf("got %v, want %v", err, errSubmissionAbandoned)
+	}
+}
@@ -0,0 +1,195 @@
+// Package trace reads and writes traces of the Vulkan calls made by asche.
+// A trace is a gob stream of a Header followed by Calls, see cmd/aschetrace for a pretty-printer.
+package trace
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Magic identifies trace files.
+const Magic = "asche-trace"
+
+// Version is the version of the trace format.
+const Version = 1
+
+// Header starts a trace.
+type Header struct {
+	Magic   string
+	Version int
+	// Start is the time the trace has been started at.
+	Start time.Time
+}
+
+// Call is a single traced Vulkan call.
+type Call struct {
+	// Time is the start of the call relative to Header.Start.
+	Time     time.Duration
+	Duration time.Duration
+	// Goroutine is the id of the goroutine that made the call.
+	Goroutine int64
+	Func      string
+	// Args are the key arguments, formatted as name=value, output arguments
+	// are captured after the call.
+	Args []string
+	// Result is the VkResult of the call, valid only if HasResult is set, calls returning void
+	// don't have one. ResultName describes results other than VK_SUCCESS.
+	Result     int32
+	ResultName string
+	HasResult  bool
+	// Err is set if a call returning an error has failed.
+	Err string
+}
+
+// Failed reports whether the call has returned an error.
+func (c *Call) Failed() bool {
+	return c.Err != "" || (c.HasResult && c.Result < 0)
+}
+
+func (c *Call) String() string {
+	s := fmt.Sprintf("%12.6fms g%-4d %s(%s)", float64(c.Time)/float64(time.Millisecond),
+		c.Goroutine, c.Func, strings.Join(c.Args, ", "))
+	switch {
+	case c.Err != "":
+		s += " = " + c.Err
+	case c.HasResult && c.Result == 0:
+		s += " = ok"
+	case c.HasResult && c.ResultName != "":
+		s += fmt.Sprintf(" = %s (%d)", c.ResultName, c.Result)
+	case c.HasResult:
+		s += fmt.Sprintf(" = %d", c.Result)
+	}
+	return s + " " + c.Duration.String()
+}
+
+// Writer writes a trace, it's safe to use from multiple goroutines. The calls are buffered,
+// failed calls flush the buffer so they are not lost if the process crashes right after.
+type Writer struct {
+	start time.Time
+
+	mu     sync.Mutex
+	buf    *bufio.Writer
+	enc    *gob.Encoder
+	closer io.Closer
+	err    error
+}
+
+// NewWriter starts a trace written to w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	buf := bufio.NewWriter(w)
+	tw := &Writer{
+		start: time.Now(),
+		buf:   buf,
+		enc:   gob.NewEncoder(buf),
+	}
+	err := tw.enc.Encode(&Header{
+		Magic:   Magic,
+		Version: Version,
+		Start:   tw.start,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// Create starts a trace written to a new file at path, Close closes the file.
+func Create(path string) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// Start gets the start time of the trace.
+func (w *Writer) Start() time.Time {
+	return w.start
+}
+
+// Write appends a call to the trace, once a write has failed all the following writes are dropped
+// and the error is returned by Flush and Close.
+func (w *Writer) Write(c *Call) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if w.err = w.enc.Encode(c); w.err == nil && c.Failed() {
+		w.err = w.buf.Flush()
+	}
+	return w.err
+}
+
+// Flush writes the buffered calls to the underlying writer.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.buf.Flush()
+	return w.err
+}
+
+// Close flushes the trace and closes the file if the trace has been started with Create.
+func (w *Writer) Close() error {
+	err := w.Flush()
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Reader reads a trace.
+type Reader struct {
+	header Header
+	dec    *gob.Decoder
+}
+
+// NewReader reads the trace header from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	tr := &Reader{
+		dec: gob.NewDecoder(bufio.NewReader(r)),
+	}
+	if err := tr.dec.Decode(&tr.header); err != nil {
+		return nil, fmt.Errorf("trace: failed to read header: %v", err)
+	}
+	if tr.header.Magic != Magic {
+		return nil, errors.New("trace: not an asche trace")
+	}
+	if tr.header.Version != Version {
+		return nil, fmt.Errorf("trace: unsupported version %d", tr.header.Version)
+	}
+	return tr, nil
+}
+
+// Header gets the header of the trace.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next reads the next call, it returns io.EOF at the end of the trace and io.ErrUnexpectedEOF
+// if the trace has been cut, e.g. when the process has crashed.
+func (r *Reader) Next() (*Call, error) {
+	var c Call
+	if err := r.dec.Decode(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,131 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		calls []Call
+	}{
+		{name: "empty"},
+		{
+			name: "calls",
+			calls: []Call{
+				{Time: time.Millisecond, Duration: time.Microsecond, Goroutine: 1, Func: "CmdDraw",
+					Args: []string{"vertexCount=3"}},
+				{Time: 2 * time.Millisecond, Goroutine: 7, Func: "QueueSubmit", HasResult: true},
+				{Func: "AcquireNextImage", Result: -1000001004, ResultName: "vulkan error: out of date",
+					HasResult: true},
+				{Func: "CreateInstance", Err: "vulkan error: instance"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range tt.calls {
+				if err := w.Write(&tt.calls[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h := r.Header(); !h.Start.Equal(w.Start()) {
+				t.Errorf("start %v, want %v", h.Start, w.Start())
+			}
+			for i := range tt.calls {
+				c, err := r.Next()
+				if err != nil {
+					t.Fatalf("call %d: %v", i, err)
+				}
+				if !reflect.DeepEqual(c, &tt.calls[i]) {
+					t.Errorf("call %d: got %+v, want %+v", i, c, &tt.calls[i])
+				}
+			}
+			if _, err := r.Next(); err != io.EOF {
+				t.Errorf("got %v at the end, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	tests := []struct {
+		name    string
+		cut     int
+		wantErr error
+	}{
+		{name: "last call cut", cut: 1, wantErr: io.ErrUnexpectedEOF},
+		{name: "last call missing", wantErr: io.EOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(&Call{Func: "CmdDraw"})
+			w.Close()
+			b := buf.Bytes()
+
+			r, err := NewReader(bytes.NewReader(b[:len(b)-tt.cut]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.cut == 0 {
+				if _, err := r.Next(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := r.Next(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFlushFailed(t *testing.T) {
+	tests := []struct {
+		name    string
+		call    Call
+		flushed bool
+	}{
+		{name: "succeeded", call: Call{Func: "QueueSubmit", HasResult: true}},
+		{name: "not ready", call: Call{Func: "GetFenceStatus", Result: 1, HasResult: true}},
+		{name: "failed", call: Call{Func: "QueueSubmit", Result: -4, HasResult: true}, flushed: true},
+		{name: "error", call: Call{Func: "CreateInstance", Err: "vulkan error: instance"}, flushed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write(&tt.call); err != nil {
+				t.Fatal(err)
+			}
+			if flushed := buf.Len() > 0; flushed != tt.flushed {
+				t.Errorf("flushed %v, want %v", flushed, tt.flushed)
+			}
+		})
+	}
+}
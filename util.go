@@ -1,21 +1,30 @@
 package asche
 
 import (
+	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"unsafe"
 
+	"github.com/vulkan-go/asche/internal/driver"
+	"github.com/vulkan-go/asche/spirv"
 	vk "github.com/vulkan-go/vulkan"
 )
 
 // InstanceExtensions gets a list of instance extensions available on the platform.
 func InstanceExtensions() (names []string, err error) {
+	return enumerateInstanceExtensions(vkd)
+}
+
+func enumerateInstanceExtensions(d driver.Driver) (names []string, err error) {
 	defer checkErr(&err)
 
 	var count uint32
-	ret := vk.EnumerateInstanceExtensionProperties("", &count, nil)
+	ret := d.EnumerateInstanceExtensionProperties("", &count, nil)
 	orPanic(NewError(ret))
 	list := make([]vk.ExtensionProperties, count)
-	ret = vk.EnumerateInstanceExtensionProperties("", &count, list)
+	ret = d.EnumerateInstanceExtensionProperties("", &count, list)
 	orPanic(NewError(ret))
 	for _, ext := range list {
 		ext.Deref()
@@ -26,13 +35,17 @@ func InstanceExtensions() (names []string, err error) {
 
 // DeviceExtensions gets a list of instance extensions available on the provided physical device.
 func DeviceExtensions(gpu vk.PhysicalDevice) (names []string, err error) {
+	return enumerateDeviceExtensions(vkd, gpu)
+}
+
+func enumerateDeviceExtensions(d driver.Driver, gpu vk.PhysicalDevice) (names []string, err error) {
 	defer checkErr(&err)
 
 	var count uint32
-	ret := vk.EnumerateDeviceExtensionProperties(gpu, "", &count, nil)
+	ret := d.EnumerateDeviceExtensionProperties(gpu, "", &count, nil)
 	orPanic(NewError(ret))
 	list := make([]vk.ExtensionProperties, count)
-	ret = vk.EnumerateDeviceExtensionProperties(gpu, "", &count, list)
+	ret = d.EnumerateDeviceExtensionProperties(gpu, "", &count, list)
 	orPanic(NewError(ret))
 	for _, ext := range list {
 		ext.Deref()
@@ -43,13 +56,17 @@ func DeviceExtensions(gpu vk.PhysicalDevice) (names []string, err error) {
 
 // ValidationLayers gets a list of validation layers available on the platform.
 func ValidationLayers() (names []string, err error) {
+	return enumerateLayers(vkd)
+}
+
+func enumerateLayers(d driver.Driver) (names []string, err error) {
 	defer checkErr(&err)
 
 	var count uint32
-	ret := vk.EnumerateInstanceLayerProperties(&count, nil)
+	ret := d.EnumerateInstanceLayerProperties(&count, nil)
 	orPanic(NewError(ret))
 	list := make([]vk.LayerProperties, count)
-	ret = vk.EnumerateInstanceLayerProperties(&count, list)
+	ret = d.EnumerateInstanceLayerProperties(&count, list)
 	orPanic(NewError(ret))
 	for _, layer := range list {
 		layer.Deref()
@@ -93,7 +110,8 @@ func FindRequiredMemoryTypeFallback(props vk.PhysicalDeviceMemoryProperties,
 }
 
 type Buffer struct {
-	// device for destroy purposes.
+	// vkd and device for destroy purposes.
+	vkd    driver.Driver
 	device vk.Device
 	// Buffer is the buffer object.
 	Buffer vk.Buffer
@@ -102,17 +120,18 @@ type Buffer struct {
 }
 
 func (b *Buffer) Destroy() {
-	vk.FreeMemory(b.device, b.Memory, nil)
-	vk.DestroyBuffer(b.device, b.Buffer, nil)
+	b.vkd.FreeMemory(b.device, b.Memory, nil)
+	b.vkd.DestroyBuffer(b.device, b.Buffer, nil)
 	b.device = nil
 }
 
 func CreateBuffer(device vk.Device, memProps vk.PhysicalDeviceMemoryProperties,
 	data []byte, usage vk.BufferUsageFlagBits) *Buffer {
 
+	d := deviceDriver(device)
 	var buffer vk.Buffer
 	var memory vk.DeviceMemory
-	ret := vk.CreateBuffer(device, &vk.BufferCreateInfo{
+	ret := d.CreateBuffer(device, &vk.BufferCreateInfo{
 		SType: vk.StructureTypeBufferCreateInfo,
 		Usage: vk.BufferUsageFlags(usage),
 		Size:  vk.DeviceSize(len(data)),
@@ -121,7 +140,7 @@ func CreateBuffer(device vk.Device, memProps vk.PhysicalDeviceMemoryProperties,
 
 	// Ask device about its memory requirements.
 	var memReqs vk.MemoryRequirements
-	vk.GetBufferMemoryRequirements(device, buffer, &memReqs)
+	d.GetBufferMemoryRequirements(device, buffer, &memReqs)
 	memReqs.Deref()
 
 	memType, ok := FindRequiredMemoryType(memProps, vk.MemoryPropertyFlagBits(memReqs.MemoryTypeBits),
@@ -131,16 +150,17 @@ func CreateBuffer(device vk.Device, memProps vk.PhysicalDeviceMemoryProperties,
 	}
 
 	// Allocate device memory and bind to the buffer.
-	ret = vk.AllocateMemory(device, &vk.MemoryAllocateInfo{
+	ret = d.AllocateMemory(device, &vk.MemoryAllocateInfo{
 		SType:           vk.StructureTypeMemoryAllocateInfo,
 		AllocationSize:  memReqs.Size,
 		MemoryTypeIndex: memType,
 	}, nil, &memory)
 	orPanic(NewError(ret), func() {
-		vk.DestroyBuffer(device, buffer, nil)
+		d.DestroyBuffer(device, buffer, nil)
 	})
-	vk.BindBufferMemory(device, buffer, memory, 0)
+	d.BindBufferMemory(device, buffer, memory, 0)
 	b := &Buffer{
+		vkd:    d,
 		device: device,
 		Buffer: buffer,
 		Memory: memory,
@@ -149,7 +169,7 @@ func CreateBuffer(device vk.Device, memProps vk.PhysicalDeviceMemoryProperties,
 	// Map the memory and dump data in there.
 	if len(data) > 0 {
 		var pData unsafe.Pointer
-		ret := vk.MapMemory(device, memory, 0, vk.DeviceSize(len(data)), 0, &pData)
+		ret := d.MapMemory(device, memory, 0, vk.DeviceSize(len(data)), 0, &pData)
 		if isError(ret) {
 			log.Printf("vulkan warning: failed to map device memory for data (len=%d)", len(data))
 			return b
@@ -158,17 +178,110 @@ func CreateBuffer(device vk.Device, memProps vk.PhysicalDeviceMemoryProperties,
 		if n != len(data) {
 			log.Printf("vulkan warning: failed to copy data, %d != %d", n, len(data))
 		}
-		vk.UnmapMemory(device, memory)
+		d.UnmapMemory(device, memory)
 	}
 	return b
 }
 
+// LoadShaderModule validates the SPIR-V binary and creates a shader module from it.
+// The data may be unaligned or byte-swapped, it's copied into properly ordered words.
+// The SPIR-V version is checked against the Vulkan version negotiated for the device,
+// devices not created by asche only get the header checked.
 func LoadShaderModule(device vk.Device, data []byte) (vk.ShaderModule, error) {
+	code, err := deviceShaderCode(device, data)
+	if err != nil {
+		return vk.NullShaderModule, fmt.Errorf("vulkan error: invalid shader code: %w", err)
+	}
+	return createShaderModule(device, code)
+}
+
+// DestroyShaderModule destroys a shader module created by LoadShaderModule, so it's released by
+// ApplicationLeakTracker as well.
+func DestroyShaderModule(device vk.Device, module vk.ShaderModule) {
+	deviceDriver(device).DestroyShaderModule(device, module, nil)
+}
+
+// LoadShaderModuleReader reads a SPIR-V binary from r and creates a shader module, see LoadShaderModule.
+func LoadShaderModuleReader(device vk.Device, r io.Reader) (vk.ShaderModule, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return vk.NullShaderModule, fmt.Errorf("vulkan error: failed to read shader: %w", err)
+	}
+	return LoadShaderModule(device, data)
+}
+
+// LoadShaderModuleFS reads a SPIR-V binary from the named file of fsys and creates a shader module,
+// see LoadShaderModule.
+func LoadShaderModuleFS(device vk.Device, fsys fs.FS, name string) (vk.ShaderModule, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		// the path error names the file
+		return vk.NullShaderModule, fmt.Errorf("vulkan error: failed to read shader: %w", err)
+	}
+	code, err := deviceShaderCode(device, data)
+	if err != nil {
+		return vk.NullShaderModule, fmt.Errorf("vulkan error: invalid shader code in %s: %w", name, err)
+	}
+	return createShaderModule(device, code)
+}
+
+// ShaderCode validates the header of a SPIR-V binary and converts it into words in host order,
+// the SPIR-V version must be supported by the Vulkan API version.
+func ShaderCode(data []byte, apiVersion vk.Version) ([]uint32, error) {
+	code, header, err := parseShaderCode(data)
+	if err == nil {
+		err = checkShaderVersion(header, apiVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("vulkan error: invalid shader code: %w", err)
+	}
+	return code, nil
+}
+
+// deviceShaderCode is ShaderCode for the Vulkan version of the device, if known.
+// The errors are not prefixed, so the callers can name the shader.
+func deviceShaderCode(device vk.Device, data []byte) ([]uint32, error) {
+	code, header, err := parseShaderCode(data)
+	if err != nil {
+		return nil, err
+	}
+	if apiVersion, ok := deviceAPIVersion(device); ok {
+		if err := checkShaderVersion(header, apiVersion); err != nil {
+			return nil, err
+		}
+	}
+	return code, nil
+}
+
+func parseShaderCode(data []byte) ([]uint32, *spirv.Header, error) {
+	code, err := spirv.Words(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := spirv.ParseHeader(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	return code, header, nil
+}
+
+func checkShaderVersion(header *spirv.Header, apiVersion vk.Version) error {
+	maxVersion := maxSPIRVVersion(apiVersion)
+	if header.Version > maxVersion {
+		return fmt.Errorf("SPIR-V %d.%d is not supported by Vulkan %d.%d, which accepts up to SPIR-V %d.%d",
+			header.VersionMajor(), header.VersionMinor(),
+			apiVersion.Major(), apiVersion.Minor(),
+			(maxVersion>>16)&0xff, (maxVersion>>8)&0xff)
+	}
+	return nil
+}
+
+func createShaderModule(device vk.Device, code []uint32) (vk.ShaderModule, error) {
 	var module vk.ShaderModule
-	ret := vk.CreateShaderModule(device, &vk.ShaderModuleCreateInfo{
+	ret := deviceDriver(device).CreateShaderModule(device, &vk.ShaderModuleCreateInfo{
 		SType:    vk.StructureTypeShaderModuleCreateInfo,
-		CodeSize: uint(len(data)),
-		PCode:    sliceUint32(data),
+		CodeSize: uint(len(code) * 4),
+		PCode:    code,
 	}, nil, &module)
 	if isError(ret) {
 		return vk.NullShaderModule, NewError(ret)
@@ -176,13 +289,16 @@ func LoadShaderModule(device vk.Device, data []byte) (vk.ShaderModule, error) {
 	return module, nil
 }
 
-func sliceUint32(data []byte) []uint32 {
-	const m = 0x7fffffff
-	return (*[m / 4]uint32)(unsafe.Pointer((*sliceHeader)(unsafe.Pointer(&data)).Data))[:len(data)/4]
-}
-
-type sliceHeader struct {
-	Data uintptr
-	Len  int
-	Cap  int
+// maxSPIRVVersion gets the highest SPIR-V version that the Vulkan API version must accept.
+func maxSPIRVVersion(apiVersion vk.Version) uint32 {
+	switch {
+	case apiVersion >= vk.Version(vk.MakeVersion(1, 3, 0)):
+		return spirv.MakeVersion(1, 6)
+	case apiVersion >= vk.Version(vk.MakeVersion(1, 2, 0)):
+		return spirv.MakeVersion(1, 5)
+	case apiVersion >= vk.Version(vk.MakeVersion(1, 1, 0)):
+		return spirv.MakeVersion(1, 3)
+	default:
+		return spirv.MakeVersion(1, 0)
+	}
 }
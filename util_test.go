@@ -0,0 +1,105 @@
+package asche
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/vulkan-go/asche/spirv"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// shaderWords is a SPIR-V header followed by an OpNop.
+func shaderWords(magic, version, bound uint32) []uint32 {
+	return []uint32{magic, version, 0, bound, 0, 1 << 16}
+}
+
+func shaderBinary(order binary.ByteOrder, magic, version, bound uint32) []byte {
+	words := shaderWords(magic, version, bound)
+	data := make([]byte, len(words)*4)
+	for i, w := range words {
+		order.PutUint32(data[i*4:], w)
+	}
+	return data
+}
+
+func TestShaderCode(t *testing.T) {
+	valid := shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(1, 0), 1)
+	tests := []struct {
+		name       string
+		data       []byte
+		apiVersion vk.Version
+		wantErr    string
+	}{
+		{name: "little endian", data: valid, apiVersion: makeVersion(1, 0)},
+		{
+			name:       "big endian",
+			data:       shaderBinary(binary.BigEndian, spirv.Magic, spirv.MakeVersion(1, 0), 1),
+			apiVersion: makeVersion(1, 0),
+		},
+		{
+			name:       "size",
+			data:       valid[:len(valid)-1],
+			apiVersion: makeVersion(1, 0),
+			wantErr:    "vulkan error: invalid shader code: spirv: module size 23 is not a multiple of 4",
+		},
+		{
+			name:       "truncated",
+			data:       valid[:16],
+			apiVersion: makeVersion(1, 0),
+			wantErr:    "vulkan error: invalid shader code: spirv: module is truncated",
+		},
+		{
+			name:       "magic",
+			data:       shaderBinary(binary.LittleEndian, 0x12345678, spirv.MakeVersion(1, 0), 1),
+			apiVersion: makeVersion(1, 0),
+			wantErr:    "vulkan error: invalid shader code: spirv: invalid magic number",
+		},
+		{
+			name:       "major version",
+			data:       shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(2, 0), 1),
+			apiVersion: makeVersion(1, 3),
+			wantErr:    "vulkan error: invalid shader code: spirv: unsupported version 0x00020000",
+		},
+		{
+			name:       "bound",
+			data:       shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(1, 0), 0),
+			apiVersion: makeVersion(1, 0),
+			wantErr:    "vulkan error: invalid shader code: spirv: ID bound must be greater than zero",
+		},
+		{
+			name:       "version supported",
+			data:       shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(1, 3), 1),
+			apiVersion: makeVersion(1, 1),
+		},
+		{
+			name:       "version not supported",
+			data:       shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(1, 5), 1),
+			apiVersion: makeVersion(1, 1),
+			wantErr: "vulkan error: invalid shader code: SPIR-V 1.5 is not supported by Vulkan 1.1, " +
+				"which accepts up to SPIR-V 1.3",
+		},
+		{
+			name:       "latest",
+			data:       shaderBinary(binary.LittleEndian, spirv.Magic, spirv.MakeVersion(1, 6), 1),
+			apiVersion: makeVersion(1, 3),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := ShaderCode(tt.data, tt.apiVersion)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := shaderWords(spirv.Magic, code[1], 1); !reflect.DeepEqual(code, want) {
+				t.Errorf("got %#x, want %#x", code, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package asche
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+#define ASCHE_STRUCTURE_TYPE_VALIDATION_FEATURES 1000247000
+
+// asche_validation_features mirrors VkValidationFeaturesEXT.
+typedef struct {
+	int32_t sType;
+	const void *pNext;
+	uint32_t enabledValidationFeatureCount;
+	const int32_t *pEnabledValidationFeatures;
+	uint32_t disabledValidationFeatureCount;
+	const int32_t *pDisabledValidationFeatures;
+} asche_validation_features;
+
+// asche_new_validation_features copies the lists along with the structure into a single block,
+// so it's released with free. It returns NULL if out of memory.
+static asche_validation_features *asche_new_validation_features(const int32_t *enables, uint32_t enable_count,
+	const int32_t *disables, uint32_t disable_count) {
+
+	asche_validation_features *f = calloc(1, sizeof(asche_validation_features) +
+		(enable_count + disable_count) * sizeof(int32_t));
+	if (f == NULL) {
+		return NULL;
+	}
+	int32_t *lists = (int32_t *)(f + 1);
+	if (enable_count > 0) {
+		memcpy(lists, enables, enable_count * sizeof(int32_t));
+	}
+	if (disable_count > 0) {
+		memcpy(lists + enable_count, disables, disable_count * sizeof(int32_t));
+	}
+	f->sType = ASCHE_STRUCTURE_TYPE_VALIDATION_FEATURES;
+	f->enabledValidationFeatureCount = enable_count;
+	f->pEnabledValidationFeatures = lists;
+	f->disabledValidationFeatureCount = disable_count;
+	f->pDisabledValidationFeatures = lists + enable_count;
+	return f;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"unsafe"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+// DefaultValidationLayer is the layer enabled by ApplicationValidation.
+var DefaultValidationLayer = "VK_LAYER_KHRONOS_validation"
+
+// ValidationFeatureDisable is a VkValidationFeatureDisableEXT value,
+// VK_EXT_validation_features is not bound by vulkan-go.
+type ValidationFeatureDisable int32
+
+const (
+	ValidationFeatureDisableAll                   ValidationFeatureDisable = 0
+	ValidationFeatureDisableShaders               ValidationFeatureDisable = 1
+	ValidationFeatureDisableThreadSafety          ValidationFeatureDisable = 2
+	ValidationFeatureDisableApiParameters         ValidationFeatureDisable = 3
+	ValidationFeatureDisableObjectLifetimes       ValidationFeatureDisable = 4
+	ValidationFeatureDisableCoreChecks            ValidationFeatureDisable = 5
+	ValidationFeatureDisableUniqueHandles         ValidationFeatureDisable = 6
+	ValidationFeatureDisableShaderValidationCache ValidationFeatureDisable = 7
+)
+
+// validationFeatureEnable is a VkValidationFeatureEnableEXT value.
+type validationFeatureEnable int32
+
+const (
+	validationFeatureEnableGpuAssisted               validationFeatureEnable = 0
+	validationFeatureEnableBestPractices             validationFeatureEnable = 2
+	validationFeatureEnableSynchronizationValidation validationFeatureEnable = 4
+)
+
+// ValidationConfig configures the validation layer, see ApplicationValidation.
+type ValidationConfig struct {
+	// BestPractices warns about valid API usage that is likely inefficient.
+	BestPractices bool
+	// GPUAssisted instruments shaders to validate descriptor indexing and buffer accesses on the GPU.
+	GPUAssisted bool
+	// Synchronization reports hazards caused by missing or incorrect synchronization.
+	Synchronization bool
+	// Disable turns off checks of the layer, e.g. ValidationFeatureDisableThreadSafety.
+	Disable []ValidationFeatureDisable
+	// IgnoreMessageIDs filters out messages by their ID, the Code printed along with each message.
+	IgnoreMessageIDs []int32
+	// Strict makes any validation error to be returned by the next Context call that returns an error.
+	Strict bool
+}
+
+func (cfg *ValidationConfig) enables() []validationFeatureEnable {
+	var enables []validationFeatureEnable
+	if cfg.BestPractices {
+		enables = append(enables, validationFeatureEnableBestPractices)
+	}
+	if cfg.GPUAssisted {
+		enables = append(enables, validationFeatureEnableGpuAssisted)
+	}
+	if cfg.Synchronization {
+		enables = append(enables, validationFeatureEnableSynchronizationValidation)
+	}
+	return enables
+}
+
+// validation filters and logs the validation messages, keeping the first error in strict mode.
+type validation struct {
+	strict bool
+	ignore map[int32]bool
+
+	mu  sync.Mutex
+	err error
+}
+
+func newValidation(cfg *ValidationConfig) *validation {
+	v := &validation{
+		strict: cfg.Strict,
+		ignore: make(map[int32]bool, len(cfg.IgnoreMessageIDs)),
+	}
+	for _, id := range cfg.IgnoreMessageIDs {
+		v.ignore[id] = true
+	}
+	return v
+}
+
+func (v *validation) callback(flags vk.DebugReportFlags, objectType vk.DebugReportObjectType,
+	object uint64, location uint, messageCode int32, pLayerPrefix string,
+	pMessage string, pUserData unsafe.Pointer) vk.Bool32 {
+
+	if v.ignore[messageCode] {
+		return vk.Bool32(vk.False)
+	}
+	dbgCallbackFunc(flags, objectType, object, location, messageCode, pLayerPrefix, pMessage, pUserData)
+	if v.strict && flags&vk.DebugReportFlags(vk.DebugReportErrorBit) != 0 {
+		v.mu.Lock()
+		if v.err == nil {
+			v.err = fmt.Errorf("vulkan error: validation failed: [%s] Code %d : %s",
+				pLayerPrefix, messageCode, pMessage)
+		}
+		v.mu.Unlock()
+	}
+	return vk.Bool32(vk.False)
+}
+
+// takeError gets the first validation error since the last call.
+func (v *validation) takeError() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	err := v.err
+	v.err = nil
+	return err
+}
+
+// checkValidation reports a validation error in strict mode unless the call has failed already.
+func (c *context) checkValidation(err *error) {
+	if c.validation == nil || *err != nil {
+		return
+	}
+	*err = c.validation.takeError()
+}
+
+// LayerExtensions gets a list of instance extensions provided by the layer.
+func LayerExtensions(layer string) (names []string, err error) {
+	return enumerateLayerExtensions(vkd, layer)
+}
+
+func enumerateLayerExtensions(d driver.Driver, layer string) (names []string, err error) {
+	defer checkErr(&err)
+
+	var count uint32
+	ret := d.EnumerateInstanceExtensionProperties(safeString(layer), &count, nil)
+	orPanic(NewError(ret))
+	list := make([]vk.ExtensionProperties, count)
+	ret = d.EnumerateInstanceExtensionProperties(safeString(layer), &count, list)
+	orPanic(NewError(ret))
+	for _, ext := range list {
+		ext.Deref()
+		names = append(names, vk.ToString(ext.ExtensionName[:]))
+	}
+	return names, err
+}
+
+// validationFeatures gets a VkValidationFeaturesEXT to chain into the instance create info,
+// it's allocated in C memory and must be released with freeValidationFeatures. It's nil if VK_EXT_validation_features
+// is not enabled or there is nothing to configure.
+func validationFeatures(cfg *ValidationConfig, instanceExtensions []string) unsafe.Pointer {
+	enables := cfg.enables()
+	if len(enables) == 0 && len(cfg.Disable) == 0 {
+		return nil
+	}
+	if !hasString(instanceExtensions, safeString("VK_EXT_validation_features")) {
+		log.Println("vulkan warning: VK_EXT_validation_features is not available, validation features are ignored")
+		return nil
+	}
+	var pEnables, pDisables *C.int32_t
+	if len(enables) > 0 {
+		pEnables = (*C.int32_t)(unsafe.Pointer(&enables[0]))
+	}
+	if len(cfg.Disable) > 0 {
+		pDisables = (*C.int32_t)(unsafe.Pointer(&cfg.Disable[0]))
+	}
+	features := C.asche_new_validation_features(pEnables, C.uint32_t(len(enables)),
+		pDisables, C.uint32_t(len(cfg.Disable)))
+	if features == nil {
+		orPanic(NewError(vk.ErrorOutOfHostMemory))
+	}
+	return unsafe.Pointer(features)
+}
+
+func freeValidationFeatures(features unsafe.Pointer) {
+	C.free(features)
+}
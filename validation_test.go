@@ -0,0 +1,100 @@
+package asche
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// validationFeaturesEXT is the Go layout of VkValidationFeaturesEXT.
+type validationFeaturesEXT struct {
+	sType         int32
+	pNext         unsafe.Pointer
+	enabledCount  uint32
+	pEnabled      *int32
+	disabledCount uint32
+	pDisabled     *int32
+}
+
+func TestValidationFeatures(t *testing.T) {
+	extensions := []string{"VK_EXT_debug_report\x00", "VK_EXT_validation_features\x00"}
+	tests := []struct {
+		name       string
+		cfg        ValidationConfig
+		extensions []string
+		enabled    []int32
+		disabled   []int32
+	}{
+		{
+			name:       "nothing to configure",
+			cfg:        ValidationConfig{Strict: true},
+			extensions: extensions,
+		},
+		{
+			name:       "extension missing",
+			cfg:        ValidationConfig{BestPractices: true},
+			extensions: extensions[:1],
+		},
+		{
+			name:       "enables",
+			cfg:        ValidationConfig{BestPractices: true, GPUAssisted: true, Synchronization: true},
+			extensions: extensions,
+			enabled:    []int32{2, 0, 4},
+		},
+		{
+			name: "disables",
+			cfg: ValidationConfig{Disable: []ValidationFeatureDisable{
+				ValidationFeatureDisableThreadSafety, ValidationFeatureDisableUniqueHandles,
+			}},
+			extensions: extensions,
+			disabled:   []int32{2, 6},
+		},
+		{
+			name: "both",
+			cfg: ValidationConfig{
+				Synchronization: true,
+				Disable:         []ValidationFeatureDisable{ValidationFeatureDisableShaders},
+			},
+			extensions: extensions,
+			enabled:    []int32{4},
+			disabled:   []int32{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := validationFeatures(&tt.cfg, tt.extensions)
+			if tt.enabled == nil && tt.disabled == nil {
+				if p != nil {
+					freeValidationFeatures(p)
+					t.Fatal("validation features chained")
+				}
+				return
+			}
+			if p == nil {
+				t.Fatal("validation features not chained")
+			}
+			defer freeValidationFeatures(p)
+
+			f := (*validationFeaturesEXT)(p)
+			if f.sType != 1000247000 {
+				t.Errorf("sType %d", f.sType)
+			}
+			if f.pNext != nil {
+				t.Error("pNext is set")
+			}
+			if got := int32s(f.pEnabled, f.enabledCount); !reflect.DeepEqual(got, tt.enabled) {
+				t.Errorf("enabled %v, want %v", got, tt.enabled)
+			}
+			if got := int32s(f.pDisabled, f.disabledCount); !reflect.DeepEqual(got, tt.disabled) {
+				t.Errorf("disabled %v, want %v", got, tt.disabled)
+			}
+		})
+	}
+}
+
+func int32s(p *int32, n uint32) []int32 {
+	if n == 0 {
+		return nil
+	}
+	return append([]int32(nil), unsafe.Slice(p, n)...)
+}
@@ -0,0 +1,146 @@
+package asche
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/vulkan-go/asche/internal/driver"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func makeVersion(major, minor int) vk.Version {
+	return vk.Version(vk.MakeVersion(major, minor, 0))
+}
+
+// promotedExtensions maps extensions promoted to core to the API version that includes them,
+// such extensions don't need to be enabled when the negotiated version is the same or higher.
+var promotedExtensions = map[string]vk.Version{
+	// instance extensions
+	"VK_KHR_get_physical_device_properties2": makeVersion(1, 1),
+	"VK_KHR_device_group_creation":           makeVersion(1, 1),
+	"VK_KHR_external_memory_capabilities":    makeVersion(1, 1),
+	"VK_KHR_external_semaphore_capabilities": makeVersion(1, 1),
+	"VK_KHR_external_fence_capabilities":     makeVersion(1, 1),
+
+	// device extensions
+	"VK_KHR_16bit_storage":                      makeVersion(1, 1),
+	"VK_KHR_bind_memory2":                       makeVersion(1, 1),
+	"VK_KHR_dedicated_allocation":               makeVersion(1, 1),
+	"VK_KHR_descriptor_update_template":         makeVersion(1, 1),
+	"VK_KHR_device_group":                       makeVersion(1, 1),
+	"VK_KHR_external_fence":                     makeVersion(1, 1),
+	"VK_KHR_external_memory":                    makeVersion(1, 1),
+	"VK_KHR_external_semaphore":                 makeVersion(1, 1),
+	"VK_KHR_get_memory_requirements2":           makeVersion(1, 1),
+	"VK_KHR_maintenance1":                       makeVersion(1, 1),
+	"VK_KHR_maintenance2":                       makeVersion(1, 1),
+	"VK_KHR_maintenance3":                       makeVersion(1, 1),
+	"VK_KHR_multiview":                          makeVersion(1, 1),
+	"VK_KHR_relaxed_block_layout":               makeVersion(1, 1),
+	"VK_KHR_sampler_ycbcr_conversion":           makeVersion(1, 1),
+	"VK_KHR_shader_draw_parameters":             makeVersion(1, 1),
+	"VK_KHR_storage_buffer_storage_class":       makeVersion(1, 1),
+	"VK_KHR_variable_pointers":                  makeVersion(1, 1),
+	"VK_KHR_8bit_storage":                       makeVersion(1, 2),
+	"VK_KHR_buffer_device_address":              makeVersion(1, 2),
+	"VK_KHR_create_renderpass2":                 makeVersion(1, 2),
+	"VK_KHR_depth_stencil_resolve":              makeVersion(1, 2),
+	"VK_KHR_draw_indirect_count":                makeVersion(1, 2),
+	"VK_KHR_driver_properties":                  makeVersion(1, 2),
+	"VK_KHR_image_format_list":                  makeVersion(1, 2),
+	"VK_KHR_imageless_framebuffer":              makeVersion(1, 2),
+	"VK_KHR_sampler_mirror_clamp_to_edge":       makeVersion(1, 2),
+	"VK_KHR_separate_depth_stencil_layouts":     makeVersion(1, 2),
+	"VK_KHR_shader_atomic_int64":                makeVersion(1, 2),
+	"VK_KHR_shader_float16_int8":                makeVersion(1, 2),
+	"VK_KHR_shader_float_controls":              makeVersion(1, 2),
+	"VK_KHR_shader_subgroup_extended_types":     makeVersion(1, 2),
+	"VK_KHR_spirv_1_4":                          makeVersion(1, 2),
+	"VK_KHR_timeline_semaphore":                 makeVersion(1, 2),
+	"VK_KHR_uniform_buffer_standard_layout":     makeVersion(1, 2),
+	"VK_KHR_vulkan_memory_model":                makeVersion(1, 2),
+	"VK_EXT_descriptor_indexing":                makeVersion(1, 2),
+	"VK_EXT_host_query_reset":                   makeVersion(1, 2),
+	"VK_EXT_sampler_filter_minmax":              makeVersion(1, 2),
+	"VK_EXT_scalar_block_layout":                makeVersion(1, 2),
+	"VK_EXT_separate_stencil_usage":             makeVersion(1, 2),
+	"VK_EXT_shader_viewport_index_layer":        makeVersion(1, 2),
+	"VK_KHR_copy_commands2":                     makeVersion(1, 3),
+	"VK_KHR_dynamic_rendering":                  makeVersion(1, 3),
+	"VK_KHR_format_feature_flags2":              makeVersion(1, 3),
+	"VK_KHR_maintenance4":                       makeVersion(1, 3),
+	"VK_KHR_shader_integer_dot_product":         makeVersion(1, 3),
+	"VK_KHR_shader_non_semantic_info":           makeVersion(1, 3),
+	"VK_KHR_shader_terminate_invocation":        makeVersion(1, 3),
+	"VK_KHR_synchronization2":                   makeVersion(1, 3),
+	"VK_KHR_zero_initialize_workgroup_memory":   makeVersion(1, 3),
+	"VK_EXT_4444_formats":                       makeVersion(1, 3),
+	"VK_EXT_extended_dynamic_state":             makeVersion(1, 3),
+	"VK_EXT_extended_dynamic_state2":            makeVersion(1, 3),
+	"VK_EXT_image_robustness":                   makeVersion(1, 3),
+	"VK_EXT_inline_uniform_block":               makeVersion(1, 3),
+	"VK_EXT_pipeline_creation_cache_control":    makeVersion(1, 3),
+	"VK_EXT_pipeline_creation_feedback":         makeVersion(1, 3),
+	"VK_EXT_private_data":                       makeVersion(1, 3),
+	"VK_EXT_shader_demote_to_helper_invocation": makeVersion(1, 3),
+	"VK_EXT_subgroup_size_control":              makeVersion(1, 3),
+	"VK_EXT_texel_buffer_alignment":             makeVersion(1, 3),
+	"VK_EXT_texture_compression_astc_hdr":       makeVersion(1, 3),
+	"VK_EXT_tooling_info":                       makeVersion(1, 3),
+	"VK_EXT_ycbcr_2plane_444_formats":           makeVersion(1, 3),
+}
+
+// IsPromoted reports whether the extension is part of the core API of the given version.
+func IsPromoted(extension string, version vk.Version) bool {
+	core, ok := promotedExtensions[strings.TrimSuffix(extension, end)]
+	return ok && core <= version
+}
+
+// dropPromoted removes the extensions that are part of the core API of the given version.
+func dropPromoted(extensions []string, version vk.Version) []string {
+	kept := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		if IsPromoted(ext, version) {
+			log.Printf("vulkan: %s is core in Vulkan %d.%d, not enabling it",
+				strings.TrimSuffix(ext, end), version.Major(), version.Minor())
+			continue
+		}
+		kept = append(kept, ext)
+	}
+	return kept
+}
+
+// InstanceVersion gets the highest API version supported by the loader,
+// 1.0 loaders don't report it so it's assumed when the query fails.
+func InstanceVersion() vk.Version {
+	return loaderVersion(vkd)
+}
+
+func loaderVersion(d driver.Driver) vk.Version {
+	var version uint32
+	if ret := d.EnumerateInstanceVersion(&version); isError(ret) || version == 0 {
+		return makeVersion(1, 0)
+	}
+	return vk.Version(version)
+}
+
+// negotiateVersion picks the highest version that doesn't exceed any of the given ones,
+// the patch number is dropped as it doesn't affect the API.
+func negotiateVersion(versions ...vk.Version) vk.Version {
+	version := makeVersion(versions[0].Major(), versions[0].Minor())
+	for _, v := range versions[1:] {
+		if v := makeVersion(v.Major(), v.Minor()); v < version {
+			version = v
+		}
+	}
+	return version
+}
+
+func checkMinVersion(what string, version, min vk.Version) error {
+	if version < makeVersion(min.Major(), min.Minor()) {
+		return fmt.Errorf("vulkan error: %s supports Vulkan %d.%d, the application requires %d.%d",
+			what, version.Major(), version.Minor(), min.Major(), min.Minor())
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package asche
+
+import (
+	"testing"
+
+	"github.com/vulkan-go/asche/internal/driver/fake"
+	vk "github.com/vulkan-go/vulkan"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions []vk.Version
+		want     vk.Version
+	}{
+		{
+			name:     "single",
+			versions: []vk.Version{makeVersion(1, 2)},
+			want:     makeVersion(1, 2),
+		},
+		{
+			name:     "lowest wins",
+			versions: []vk.Version{makeVersion(1, 3), makeVersion(1, 1), makeVersion(1, 2)},
+			want:     makeVersion(1, 1),
+		},
+		{
+			name:     "patch dropped",
+			versions: []vk.Version{vk.Version(vk.MakeVersion(1, 2, 189)), vk.Version(vk.MakeVersion(1, 3, 4))},
+			want:     makeVersion(1, 2),
+		},
+		{
+			name:     "major before minor",
+			versions: []vk.Version{makeVersion(2, 0), makeVersion(1, 3)},
+			want:     makeVersion(1, 3),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateVersion(tt.versions...); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMinVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version vk.Version
+		min     vk.Version
+		wantErr string
+	}{
+		{name: "equal", version: makeVersion(1, 1), min: makeVersion(1, 1)},
+		{name: "higher", version: makeVersion(1, 3), min: makeVersion(1, 1)},
+		{name: "patch ignored", version: makeVersion(1, 1), min: vk.Version(vk.MakeVersion(1, 1, 100))},
+		{
+			name:    "lower",
+			version: makeVersion(1, 0), min: makeVersion(1, 2),
+			wantErr: "vulkan error: the loader supports Vulkan 1.0, the application requires 1.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkMinVersion("the loader", tt.version, tt.min)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("got %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoaderVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		version uint32
+		fail    vk.Result
+		want    vk.Version
+	}{
+		{name: "reported", version: vk.MakeVersion(1, 3, 250), want: vk.Version(vk.MakeVersion(1, 3, 250))},
+		{name: "not reported", version: 0, want: makeVersion(1, 0)},
+		{name: "query failed", version: vk.MakeVersion(1, 3, 0), fail: vk.ErrorOutOfHostMemory, want: makeVersion(1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd := fake.New()
+			fd.InstanceVersion = tt.version
+			if tt.fail != vk.Success {
+				fd.Fail = func(call string) vk.Result {
+					if call == "EnumerateInstanceVersion" {
+						return tt.fail
+					}
+					return vk.Success
+				}
+			}
+			if got := loaderVersion(fd); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
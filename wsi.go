@@ -0,0 +1,64 @@
+package asche
+
+import (
+	"log"
+	"runtime"
+)
+
+// surfaceExtensions lists the window system surface extensions by GOOS,
+// all the ones reported by the loader are enabled so any window system works.
+var surfaceExtensions = map[string][]string{
+	"linux":   {"VK_KHR_xlib_surface", "VK_KHR_xcb_surface", "VK_KHR_wayland_surface"},
+	"freebsd": {"VK_KHR_xlib_surface", "VK_KHR_xcb_surface", "VK_KHR_wayland_surface"},
+	"openbsd": {"VK_KHR_xlib_surface", "VK_KHR_xcb_surface", "VK_KHR_wayland_surface"},
+	"netbsd":  {"VK_KHR_xlib_surface", "VK_KHR_xcb_surface", "VK_KHR_wayland_surface"},
+	"windows": {"VK_KHR_win32_surface"},
+	"android": {"VK_KHR_android_surface"},
+	"darwin":  {"VK_EXT_metal_surface", "VK_MVK_macos_surface"},
+	"ios":     {"VK_EXT_metal_surface", "VK_MVK_ios_surface"},
+}
+
+// wsiExtensionsRequired reports whether the surface and swapchain extensions must be added
+// to the ones requested by the application.
+func wsiExtensionsRequired(app Application) bool {
+	if iface, ok := app.(ApplicationExplicitExtensions); ok && iface.VulkanExplicitExtensions() {
+		return false
+	}
+	return app.VulkanMode().Has(VulkanPresent)
+}
+
+// wsiInstanceExtensions gets VK_KHR_surface and the surface extensions
+// of the current platform that are available.
+func wsiInstanceExtensions(available []string) []string {
+	extensions := []string{"VK_KHR_surface\x00"}
+	candidates := safeStrings(append([]string(nil), surfaceExtensions[runtime.GOOS]...))
+	platform, _ := checkExisting(available, candidates)
+	if len(platform) == 0 {
+		log.Println("vulkan warning: no surface extensions available for", runtime.GOOS)
+	}
+	return append(extensions, platform...)
+}
+
+// wsiDeviceExtensions gets the device extensions required for presentation.
+func wsiDeviceExtensions() []string {
+	return []string{"VK_KHR_swapchain\x00"}
+}
+
+// mergeExtensions appends the extensions not listed yet.
+func mergeExtensions(list []string, extensions ...string) []string {
+	for _, ext := range extensions {
+		if !hasString(list, ext) {
+			list = append(list, ext)
+		}
+	}
+	return list
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}